@@ -0,0 +1,583 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+
+	"github.com/nao1215/gorky/golden"
+)
+
+// newFlagSet returns the flag set of a command.
+func newFlagSet(command string, usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: golden %s\n", usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// listCommand lists the fixtures, their size and the package owning them.
+func listCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("list", "list [-sort path|size]", stderr)
+	sortBy := flags.String("sort", "path", "sort the fixtures by `path` or size (largest first)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	switch *sortBy {
+	case "path":
+	case "size":
+		sort.SliceStable(fixtures, func(i, j int) bool {
+			return fixtures[i].size > fixtures[j].size
+		})
+	default:
+		return fmt.Errorf("unknown sort order %q", *sortBy)
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "SIZE\t PACKAGE\t FIXTURE\t")
+	var total int64
+	for _, f := range fixtures {
+		total += f.size
+		fmt.Fprintf(w, "%s\t %s\t %s\t\n", humanSize(f.size), f.pkg, filepath.ToSlash(f.path))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "%d fixtures, %s in total\n", len(fixtures), humanSize(total))
+	return nil
+}
+
+// dupsCommand lists the fixtures whose content is identical.
+func dupsCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("dups", "dups", stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	groups := map[[sha256.Size]byte][]fixture{}
+	var order [][sha256.Size]byte
+	for _, f := range fixtures {
+		data, err := os.ReadFile(filepath.Join(m.root, f.path))
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(data)
+		if _, ok := groups[sum]; !ok {
+			order = append(order, sum)
+		}
+		groups[sum] = append(groups[sum], f)
+	}
+
+	duplicates := 0
+	for _, sum := range order {
+		group := groups[sum]
+		if len(group) < 2 {
+			continue
+		}
+
+		if duplicates > 0 {
+			fmt.Fprintln(stdout)
+		}
+		duplicates++
+		fmt.Fprintf(stdout, "%d identical fixtures (%s each):\n", len(group), humanSize(group[0].size))
+		for _, f := range group {
+			fmt.Fprintf(stdout, "  %s\n", filepath.ToSlash(f.path))
+		}
+	}
+
+	if duplicates == 0 {
+		fmt.Fprintln(stdout, "no duplicate fixtures")
+	}
+	return nil
+}
+
+// normalizeCommand normalizes the line endings of the text fixtures and the
+// permissions of the fixtures and their directories.
+func normalizeCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("normalize", "normalize [-line-endings lf|crlf|keep] [-file-perms 0644] [-dir-perms 0755] [-n]", stderr)
+	lineEndings := flags.String("line-endings", "lf", "convert the line endings of text fixtures to `lf`, crlf or keep them")
+	filePerms := flags.String("file-perms", "0644", "permissions of the fixtures, empty to keep them")
+	dirPerms := flags.String("dir-perms", "0755", "permissions of the fixture directories, empty to keep them")
+	dryRun := flags.Bool("n", false, "only print the changes")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var newline []byte
+	switch *lineEndings {
+	case "lf":
+		newline = []byte("\n")
+	case "crlf":
+		newline = []byte("\r\n")
+	case "keep":
+	default:
+		return fmt.Errorf("unknown line endings %q", *lineEndings)
+	}
+
+	fileMode, err := parsePerms(*filePerms)
+	if err != nil {
+		return err
+	}
+	dirMode, err := parsePerms(*dirPerms)
+	if err != nil {
+		return err
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	dirs := map[string]bool{}
+	for _, f := range fixtures {
+		p := filepath.Join(m.root, f.path)
+		for dir := filepath.Dir(f.path); dir != f.pkgDir && dir != "."; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+
+		if newline != nil {
+			changed, err := normalizeLineEndings(p, newline, *dryRun)
+			if err != nil {
+				return err
+			}
+			if changed {
+				fmt.Fprintf(stdout, "line endings: %s\n", filepath.ToSlash(f.path))
+			}
+		}
+
+		if err := normalizePerms(stdout, m.root, f.path, fileMode, *dryRun); err != nil {
+			return err
+		}
+	}
+
+	sortedDirs := make([]string, 0, len(dirs))
+	for dir := range dirs {
+		sortedDirs = append(sortedDirs, dir)
+	}
+	sort.Strings(sortedDirs)
+	for _, dir := range sortedDirs {
+		if err := normalizePerms(stdout, m.root, dir, dirMode, *dryRun); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parsePerms parses octal permissions. An empty string disables the change.
+func parsePerms(perms string) (os.FileMode, error) {
+	if perms == "" {
+		return 0, nil
+	}
+
+	mode, err := strconv.ParseUint(perms, 8, 32)
+	if err != nil || mode > 0777 {
+		return 0, fmt.Errorf("invalid permissions %q", perms)
+	}
+	return os.FileMode(mode), nil
+}
+
+// normalizeLineEndings rewrites the line endings of a text fixture. Binary and
+// compressed fixtures are left untouched.
+func normalizeLineEndings(p string, newline []byte, dryRun bool) (bool, error) {
+	if strings.HasSuffix(p, ".gz") || strings.HasSuffix(p, ".zst") || strings.HasSuffix(p, ".png") {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return false, err
+	}
+	if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+		return false, nil
+	}
+
+	// a bare \r is content, not a line ending
+	normalized := bytes.Replace(data, []byte("\r\n"), []byte("\n"), -1)
+	if !bytes.Equal(newline, []byte("\n")) {
+		normalized = bytes.Replace(normalized, []byte("\n"), newline, -1)
+	}
+	if bytes.Equal(data, normalized) {
+		return false, nil
+	}
+
+	if dryRun {
+		return true, nil
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(p, normalized, info.Mode().Perm())
+}
+
+// normalizePerms changes the permissions of the file or directory.
+func normalizePerms(stdout io.Writer, root string, rel string, mode os.FileMode, dryRun bool) error {
+	if mode == 0 {
+		return nil
+	}
+
+	p := filepath.Join(root, rel)
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Perm() == mode {
+		return nil
+	}
+
+	fmt.Fprintf(stdout, "permissions %#o -> %#o: %s\n", info.Mode().Perm(), mode, filepath.ToSlash(rel))
+	if dryRun {
+		return nil
+	}
+	return os.Chmod(p, mode)
+}
+
+// updateCommand runs `go test -update` for the packages owning fixtures that
+// match one of the globs. Packages whose tests don't import the golden package
+// are skipped, since they don't define the flag.
+func updateCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("update", "update [-clean] [-n] <glob>...", stderr)
+	clean := flags.Bool("clean", false, "pass -clean to remove old fixtures")
+	dryRun := flags.Bool("n", false, "only print the go test command")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return errors.New("at least one glob is required")
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	packages, err := matchingPackages(fixtures, flags.Args())
+	if err != nil {
+		return err
+	}
+	if len(packages) == 0 {
+		return fmt.Errorf("no fixture matches %s", strings.Join(flags.Args(), " "))
+	}
+
+	// go test fails with "flag provided but not defined: -update" for the
+	// packages whose tests don't import the golden package
+	var testable []string
+	for _, pkg := range packages {
+		ok, err := testsImport(filepath.Join(m.root, filepath.FromSlash(pkg)), goldenImportPath)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintf(stderr, "skipping %s: its tests don't import %s and have no -update flag\n", pkg, goldenImportPath)
+			continue
+		}
+		testable = append(testable, pkg)
+	}
+	if len(testable) == 0 {
+		return fmt.Errorf("no package owning a matching fixture imports %s in its tests", goldenImportPath)
+	}
+	packages = testable
+
+	goArgs := []string{"test"}
+	goArgs = append(goArgs, packages...)
+	goArgs = append(goArgs, "-update")
+	if *clean {
+		goArgs = append(goArgs, "-clean")
+	}
+
+	fmt.Fprintf(stdout, "go %s\n", strings.Join(goArgs, " "))
+	if *dryRun {
+		return nil
+	}
+
+	cmd := exec.Command("go", goArgs...)
+	cmd.Dir = m.root
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
+
+// goldenImportPath is the import path of the package defining the -update flag.
+var goldenImportPath = reflect.TypeOf((*golden.Golden)(nil)).Elem().PkgPath()
+
+// testsImport reports whether one of the test files in dir imports the
+// package.
+func testsImport(dir string, importPath string) (bool, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*_test.go"))
+	if err != nil {
+		return false, err
+	}
+
+	fset := token.NewFileSet()
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, name, nil, parser.ImportsOnly)
+		if err != nil {
+			return false, err
+		}
+		for _, spec := range file.Imports {
+			if path, err := strconv.Unquote(spec.Path.Value); err == nil && path == importPath {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// matchingPackages returns the relative package paths (`./pkg`) owning a
+// fixture whose module relative path or base name matches one of the globs.
+func matchingPackages(fixtures []fixture, globs []string) ([]string, error) {
+	seen := map[string]bool{}
+	var packages []string
+
+	for _, f := range fixtures {
+		rel := filepath.ToSlash(f.path)
+		for _, glob := range globs {
+			matchPath, err := filepath.Match(glob, rel)
+			if err != nil {
+				return nil, err
+			}
+			matchBase, _ := filepath.Match(glob, filepath.Base(rel))
+			if !matchPath && !matchBase {
+				continue
+			}
+
+			pkg := "./" + filepath.ToSlash(f.pkgDir)
+			if f.pkgDir == "." {
+				pkg = "."
+			}
+			if !seen[pkg] {
+				seen[pkg] = true
+				packages = append(packages, pkg)
+			}
+			break
+		}
+	}
+
+	sort.Strings(packages)
+	return packages, nil
+}
+
+// lintCommand reports the fixtures whose metadata header refers to a test that
+// no longer exists.
+func lintCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("lint", "lint", stderr)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	linted := map[string]bool{}
+	count := 0
+	for _, f := range fixtures {
+		if linted[f.pkgDir] {
+			continue
+		}
+		linted[f.pkgDir] = true
+
+		pkgDir := filepath.Join(m.root, f.pkgDir)
+		issues, err := golden.LintFixtures(filepath.Join(pkgDir, cfg.fixtureDir), pkgDir)
+		if err != nil {
+			return err
+		}
+
+		for _, issue := range issues {
+			if !isFixture(filepath.Base(issue.File), cfg.suffix) {
+				continue
+			}
+			count++
+			rel, err := filepath.Rel(m.root, issue.File)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(stdout, "%s: %s\n", filepath.ToSlash(rel), issue.Message)
+		}
+	}
+
+	if count > 0 {
+		return fmt.Errorf("%d issues found", count)
+	}
+	return nil
+}
+
+// churn is the change history of a fixture.
+type churn struct {
+	fixture fixture
+	// changes is the number of commits changing the fixture.
+	changes int
+	// last is the date of the last commit changing the fixture.
+	last string
+	// tests are the tests using the fixture according to the manifests.
+	tests map[string]bool
+}
+
+// churnCommand lists the fixtures changed by the most commits, with the tests
+// using them according to the manifests written by `go test -golden-manifest`.
+func churnCommand(m module, cfg config, args []string, stdout io.Writer, stderr io.Writer) error {
+	flags := newFlagSet("churn", "churn [-n count] [-since date] [-manifests dir]", stderr)
+	limit := flags.Int("n", 20, "list at most `count` fixtures, 0 lists all")
+	since := flags.String("since", "", "only count the commits more recent than `date`")
+	manifests := flags.String("manifests", "", "read the manifests written by go test -golden-manifest from `dir`")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	fixtures, err := findFixtures(m, cfg)
+	if err != nil {
+		return err
+	}
+
+	churns := make(map[string]*churn, len(fixtures))
+	for _, f := range fixtures {
+		churns[filepath.ToSlash(f.path)] = &churn{fixture: f, tests: map[string]bool{}}
+	}
+
+	if err := readGitChurn(m.root, *since, churns); err != nil {
+		return err
+	}
+	if *manifests != "" {
+		if err := readManifestTests(m.root, *manifests, churns); err != nil {
+			return err
+		}
+	}
+
+	var sorted []*churn
+	for _, c := range churns {
+		if c.changes > 0 {
+			sorted = append(sorted, c)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].changes != sorted[j].changes {
+			return sorted[i].changes > sorted[j].changes
+		}
+		return sorted[i].fixture.path < sorted[j].fixture.path
+	})
+	if *limit > 0 && len(sorted) > *limit {
+		sorted = sorted[:*limit]
+	}
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGES\tLAST CHANGED\tTESTS\tFIXTURE")
+	for _, c := range sorted {
+		tests := "-"
+		if *manifests != "" {
+			names := make([]string, 0, len(c.tests))
+			for name := range c.tests {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			tests = strconv.Itoa(len(names))
+			if len(names) > 0 {
+				tests += " (" + strings.Join(names, ", ") + ")"
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", c.changes, c.last, tests, filepath.ToSlash(c.fixture.path))
+	}
+	return w.Flush()
+}
+
+// gitCommitPrefix marks the commit lines of the git log output of
+// readGitChurn.
+const gitCommitPrefix = "commit "
+
+// readGitChurn counts the commits changing every fixture with `git log`.
+func readGitChurn(root string, since string, churns map[string]*churn) error {
+	gitArgs := []string{"log", "--relative", "--name-only", "--format=" + gitCommitPrefix + "%as"}
+	if since != "" {
+		gitArgs = append(gitArgs, "--since="+since)
+	}
+
+	cmd := exec.Command("git", gitArgs...)
+	cmd.Dir = root
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("git log: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	// the log is ordered from the newest to the oldest commit
+	date := ""
+	for _, line := range strings.Split(string(out), "\n") {
+		switch {
+		case strings.HasPrefix(line, gitCommitPrefix):
+			date = strings.TrimPrefix(line, gitCommitPrefix)
+		case line != "":
+			if c, ok := churns[line]; ok {
+				c.changes++
+				if c.last == "" {
+					c.last = date
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// readManifestTests records the tests using every fixture from the manifests
+// in dir.
+func readManifestTests(root string, dir string, churns map[string]*churn) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		manifest, err := golden.ReadManifest(file)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		for _, entry := range manifest.Fixtures {
+			p := filepath.FromSlash(entry.Path)
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(manifest.Dir, p)
+			}
+			rel, err := filepath.Rel(root, p)
+			if err != nil {
+				continue
+			}
+			if c, ok := churns[filepath.ToSlash(rel)]; ok {
+				c.tests[entry.Test] = true
+			}
+		}
+	}
+	return nil
+}
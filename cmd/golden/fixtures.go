@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// config holds the global flags.
+type config struct {
+	dir        string
+	fixtureDir string
+	suffix     string
+}
+
+// module is the Go module the command operates on.
+type module struct {
+	// root is the directory containing the go.mod file.
+	root string
+	// path is the module path declared in the go.mod file.
+	path string
+}
+
+// fixture is a golden file found in the module.
+type fixture struct {
+	// path is the path of the fixture, relative to the module root.
+	path string
+	// pkg is the import path of the package owning the fixture.
+	pkg string
+	// pkgDir is the directory of the package, relative to the module root.
+	pkgDir string
+	// size is the size of the fixture in bytes.
+	size int64
+}
+
+// findModule looks for the go.mod file in dir and its parents.
+func findModule(dir string) (module, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return module{}, err
+	}
+
+	for {
+		modPath, err := readModulePath(filepath.Join(abs, "go.mod"))
+		if err == nil {
+			return module{root: abs, path: modPath}, nil
+		}
+		if !os.IsNotExist(err) {
+			return module{}, err
+		}
+
+		parent := filepath.Dir(abs)
+		if parent == abs {
+			return module{}, fmt.Errorf("no go.mod found in %s or any parent directory", dir)
+		}
+		abs = parent
+	}
+}
+
+// readModulePath returns the module path declared in the go.mod file.
+func readModulePath(goMod string) (string, error) {
+	f, err := os.Open(goMod)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`), nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", errors.New("go.mod does not declare a module path")
+}
+
+// findFixtures returns the golden files stored in the fixture directories of
+// the packages of the module, sorted by path.
+func findFixtures(m module, cfg config) ([]fixture, error) {
+	var fixtures []fixture
+
+	err := filepath.Walk(m.root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			return nil
+		}
+
+		name := info.Name()
+		if p != m.root && (name == "vendor" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+			return filepath.SkipDir
+		}
+		if p != m.root && isModuleRoot(p) {
+			return filepath.SkipDir
+		}
+		if name != cfg.fixtureDir {
+			return nil
+		}
+
+		found, err := fixturesIn(m, p, cfg.suffix)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, found...)
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(fixtures, func(i, j int) bool {
+		return fixtures[i].path < fixtures[j].path
+	})
+	return fixtures, nil
+}
+
+// fixturesIn returns the golden files in the fixture directory.
+func fixturesIn(m module, fixtureDir string, suffix string) ([]fixture, error) {
+	pkgDir, err := filepath.Rel(m.root, filepath.Dir(fixtureDir))
+	if err != nil {
+		return nil, err
+	}
+	pkg := m.path
+	if pkgDir != "." {
+		pkg = path.Join(m.path, filepath.ToSlash(pkgDir))
+	}
+
+	var fixtures []fixture
+	err = filepath.Walk(fixtureDir, func(p string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !info.Mode().IsRegular() {
+			return err
+		}
+		if !isFixture(info.Name(), suffix) {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.root, p)
+		if err != nil {
+			return err
+		}
+		fixtures = append(fixtures, fixture{path: rel, pkg: pkg, pkgDir: pkgDir, size: info.Size()})
+		return nil
+	})
+	return fixtures, err
+}
+
+// fixtureExtensions are appended to the suffix of compressed and image
+// fixtures.
+var fixtureExtensions = []string{".gz", ".zst", ".png"}
+
+// isFixture reports whether the file name ends with the fixture suffix,
+// optionally followed by the extension of a compressed or image fixture. The
+// visual diffs of image fixtures, `*.golden.diff.png`, are no fixtures.
+func isFixture(name string, suffix string) bool {
+	for _, ext := range fixtureExtensions {
+		if strings.HasSuffix(name, ext) {
+			name = strings.TrimSuffix(name, ext)
+			break
+		}
+	}
+	return strings.HasSuffix(name, suffix)
+}
+
+// isModuleRoot reports whether the directory contains a nested module, whose
+// fixtures are not part of the current module.
+func isModuleRoot(dir string) bool {
+	_, err := os.Stat(filepath.Join(dir, "go.mod"))
+	return err == nil
+}
+
+// humanSize formats the size in bytes with a binary unit.
+func humanSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
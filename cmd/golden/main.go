@@ -0,0 +1,100 @@
+// Command golden manages the golden test fixtures of a Go module.
+//
+// Usage:
+//
+//	golden [flags] <command> [arguments]
+//
+// The commands are:
+//
+//	list                  list the fixtures, their size and the package owning them
+//	dups                  list the fixtures whose content is identical
+//	normalize             normalize line endings and permissions of the fixtures
+//	update <glob>...      run `go test -update` for the packages whose fixtures match a glob
+//	lint                  report fixtures produced by tests that no longer exist
+//	churn                 list the fixtures changed by the most commits
+//
+// The fixtures are searched in the fixture directories (`testdata` by default)
+// of every package of the module that contains the working directory. The
+// update command skips the packages whose tests don't import the golden
+// package, since only that package defines the -update flag.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// usage is printed when the command line is invalid.
+const usage = `Usage: golden [flags] <command> [arguments]
+
+Commands:
+  list              list the fixtures, their size and the package owning them
+  dups              list the fixtures whose content is identical
+  normalize         normalize line endings and permissions of the fixtures
+  update <glob>...  run "go test -update" for the packages whose fixtures match a glob
+  lint              report fixtures produced by tests that no longer exist
+  churn             list the fixtures changed by the most commits
+
+Flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the exit code.
+func run(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("golden", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+
+	var cfg config
+	flags.StringVar(&cfg.dir, "C", ".", "run in the module containing this directory")
+	flags.StringVar(&cfg.fixtureDir, "fixture-dir", "testdata", "name of the fixture directories")
+	flags.StringVar(&cfg.suffix, "suffix", ".golden", "only handle fixtures whose name ends with this suffix, optionally followed by .gz, .zst or .png")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+
+	m, err := findModule(cfg.dir)
+	if err != nil {
+		fmt.Fprintf(stderr, "golden: %s\n", err)
+		return 1
+	}
+
+	command, commandArgs := flags.Arg(0), flags.Args()[1:]
+	switch command {
+	case "list":
+		err = listCommand(m, cfg, commandArgs, stdout, stderr)
+	case "dups":
+		err = dupsCommand(m, cfg, commandArgs, stdout, stderr)
+	case "normalize":
+		err = normalizeCommand(m, cfg, commandArgs, stdout, stderr)
+	case "update":
+		err = updateCommand(m, cfg, commandArgs, stdout, stderr)
+	case "lint":
+		err = lintCommand(m, cfg, commandArgs, stdout, stderr)
+	case "churn":
+		err = churnCommand(m, cfg, commandArgs, stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "golden: unknown command %q\n", command)
+		flags.Usage()
+		return 2
+	}
+
+	if err != nil {
+		fmt.Fprintf(stderr, "golden %s: %s\n", command, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestModule creates a module with fixtures in two packages and a nested
+// module whose fixtures must be ignored.
+func newTestModule(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	files := map[string]string{
+		"go.mod":                              "module example.com/m\n\ngo 1.19\n",
+		"testdata/root.golden":                "root\n",
+		"a/a_test.go":                         "package a\n\nimport (\n\t\"testing\"\n\n\t\"github.com/nao1215/gorky/golden\"\n)\n\nfunc TestA(t *testing.T) { golden.New(t) }\n",
+		"a/testdata/one.golden":               "same\r\ncontent\r\n",
+		"a/testdata/sub/two.golden":           "same\r\ncontent\r\n",
+		"a/testdata/input.json":               "{}",
+		"b/testdata/big.golden":               "0123456789abcdef0123456789abcdef",
+		"nested/go.mod":                       "module example.com/nested\n",
+		"nested/testdata/ignored.golden":      "ignored\n",
+		"vendor/x/testdata/vendored.golden":   "vendored\n",
+		".hidden/testdata/hidden.golden":      "hidden\n",
+		"_ignored/testdata/underscore.golden": "underscore\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	}
+
+	return root
+}
+
+func runCommand(t *testing.T, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestFindModule(t *testing.T) {
+	root := newTestModule(t)
+
+	m, err := findModule(filepath.Join(root, "a", "testdata"))
+	require.NoError(t, err)
+	assert.Equal(t, root, m.root)
+	assert.Equal(t, "example.com/m", m.path)
+
+	_, err = findModule(string(filepath.Separator))
+	assert.Error(t, err)
+}
+
+func TestFindFixtures(t *testing.T) {
+	root := newTestModule(t)
+	m, err := findModule(root)
+	require.NoError(t, err)
+
+	fixtures, err := findFixtures(m, config{fixtureDir: "testdata", suffix: ".golden"})
+	require.NoError(t, err)
+
+	var paths, pkgs []string
+	for _, f := range fixtures {
+		paths = append(paths, filepath.ToSlash(f.path))
+		pkgs = append(pkgs, f.pkg)
+	}
+	assert.Equal(t, []string{
+		"a/testdata/one.golden",
+		"a/testdata/sub/two.golden",
+		"b/testdata/big.golden",
+		"testdata/root.golden",
+	}, paths)
+	assert.Equal(t, []string{
+		"example.com/m/a",
+		"example.com/m/a",
+		"example.com/m/b",
+		"example.com/m",
+	}, pkgs)
+}
+
+func TestIsFixture(t *testing.T) {
+	assert.True(t, isFixture("one.golden", ".golden"))
+	assert.True(t, isFixture("one.golden.gz", ".golden"))
+	assert.True(t, isFixture("one.golden.zst", ".golden"))
+	assert.True(t, isFixture("image.golden.png", ".golden"))
+	assert.False(t, isFixture("image.golden.diff.png", ".golden"))
+	assert.False(t, isFixture("one.golden.bak", ".golden"))
+	assert.False(t, isFixture("input.json", ".golden"))
+	assert.True(t, isFixture("input.json", ".json"))
+}
+
+func TestHumanSize(t *testing.T) {
+	assert.Equal(t, "0 B", humanSize(0))
+	assert.Equal(t, "1023 B", humanSize(1023))
+	assert.Equal(t, "1.0 KiB", humanSize(1024))
+	assert.Equal(t, "1.5 MiB", humanSize(1536*1024))
+}
+
+func TestRun(t *testing.T) {
+	code, _, stderr := runCommand(t)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, "Usage: golden")
+
+	code, _, stderr = runCommand(t, "-C", t.TempDir(), "unknown")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no go.mod found")
+
+	root := newTestModule(t)
+	code, _, stderr = runCommand(t, "-C", root, "unknown")
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr, `unknown command "unknown"`)
+}
+
+func TestList(t *testing.T) {
+	root := newTestModule(t)
+
+	code, stdout, _ := runCommand(t, "-C", root, "list", "-sort", "size")
+	require.Equal(t, 0, code)
+	assert.Equal(t, ""+
+		"  SIZE           PACKAGE                     FIXTURE\n"+
+		"  32 B   example.com/m/b       b/testdata/big.golden\n"+
+		"  15 B   example.com/m/a       a/testdata/one.golden\n"+
+		"  15 B   example.com/m/a   a/testdata/sub/two.golden\n"+
+		"   5 B     example.com/m        testdata/root.golden\n"+
+		"4 fixtures, 67 B in total\n", stdout)
+
+	code, _, stderr := runCommand(t, "-C", root, "list", "-sort", "name")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `unknown sort order "name"`)
+}
+
+func TestDups(t *testing.T) {
+	root := newTestModule(t)
+
+	code, stdout, _ := runCommand(t, "-C", root, "dups")
+	require.Equal(t, 0, code)
+	assert.Equal(t, ""+
+		"2 identical fixtures (15 B each):\n"+
+		"  a/testdata/one.golden\n"+
+		"  a/testdata/sub/two.golden\n", stdout)
+
+	code, stdout, _ = runCommand(t, "-C", root, "-suffix", ".json", "dups")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "no duplicate fixtures\n", stdout)
+}
+
+func TestNormalize(t *testing.T) {
+	root := newTestModule(t)
+	one := filepath.Join(root, "a", "testdata", "one.golden")
+	sub := filepath.Join(root, "a", "testdata", "sub")
+	require.NoError(t, os.Chmod(one, 0600))
+	require.NoError(t, os.Chmod(sub, 0700))
+
+	code, stdout, _ := runCommand(t, "-C", root, "normalize", "-n")
+	require.Equal(t, 0, code)
+	assert.Equal(t, ""+
+		"line endings: a/testdata/one.golden\n"+
+		"permissions 0600 -> 0644: a/testdata/one.golden\n"+
+		"line endings: a/testdata/sub/two.golden\n"+
+		"permissions 0700 -> 0755: a/testdata/sub\n", stdout)
+
+	data, err := os.ReadFile(one)
+	require.NoError(t, err)
+	assert.Equal(t, "same\r\ncontent\r\n", string(data))
+
+	code, _, _ = runCommand(t, "-C", root, "normalize")
+	require.Equal(t, 0, code)
+
+	data, err = os.ReadFile(one)
+	require.NoError(t, err)
+	assert.Equal(t, "same\ncontent\n", string(data))
+
+	info, err := os.Stat(one)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0644), info.Mode().Perm())
+
+	info, err = os.Stat(sub)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	code, stdout, _ = runCommand(t, "-C", root, "normalize")
+	require.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+
+	// a bare carriage return is content, like a progress bar redrawing a line
+	progress := filepath.Join(root, "a", "testdata", "progress.golden")
+	require.NoError(t, os.WriteFile(progress, []byte("10%\r100%\n"), 0644))
+	code, stdout, _ = runCommand(t, "-C", root, "normalize")
+	require.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+
+	data, err = os.ReadFile(progress)
+	require.NoError(t, err)
+	assert.Equal(t, "10%\r100%\n", string(data))
+
+	code, _, stderr := runCommand(t, "-C", root, "normalize", "-file-perms", "999")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, `invalid permissions "999"`)
+}
+
+func TestUpdate(t *testing.T) {
+	root := newTestModule(t)
+
+	code, stdout, stderr := runCommand(t, "-C", root, "update", "-n", "-clean", "one*", "testdata/*")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "go test ./a -update -clean\n", stdout)
+	assert.Contains(t, stderr, "skipping .: its tests don't import github.com/nao1215/gorky/golden")
+
+	code, _, stderr = runCommand(t, "-C", root, "update", "-n", "root*")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no package owning a matching fixture imports github.com/nao1215/gorky/golden")
+
+	code, _, stderr = runCommand(t, "-C", root, "update", "-n", "missing*")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "no fixture matches missing*")
+
+	code, _, stderr = runCommand(t, "-C", root, "update")
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr, "at least one glob is required")
+}
+
+func TestLint(t *testing.T) {
+	root := newTestModule(t)
+
+	code, stdout, _ := runCommand(t, "-C", root, "lint")
+	require.Equal(t, 0, code)
+	assert.Empty(t, stdout)
+
+	stale := "golden-metadata: v1\n" +
+		"test: TestRemoved/sub\n" +
+		"package: example.com/m/a\n" +
+		"go: go1.21\n" +
+		"updated: 2023-01-02T03:04:05Z\n" +
+		"content-type: text/plain\n" +
+		"---\n" +
+		"content\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "a", "testdata", "stale.golden"), []byte(stale), 0644))
+
+	code, stdout, stderr := runCommand(t, "-C", root, "lint")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "a/testdata/stale.golden: produced by TestRemoved which no longer exists\n", stdout)
+	assert.Contains(t, stderr, "1 issues found")
+}
+
+func TestChurn(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	root := newTestModule(t)
+
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", args...)
+		cmd.Dir = root
+		cmd.Env = append(os.Environ(),
+			"GIT_AUTHOR_NAME=gopher", "GIT_AUTHOR_EMAIL=gopher@example.com",
+			"GIT_COMMITTER_NAME=gopher", "GIT_COMMITTER_EMAIL=gopher@example.com",
+			"GIT_AUTHOR_DATE=2023-05-01T10:00:00Z", "GIT_COMMITTER_DATE=2023-05-01T10:00:00Z",
+		)
+		out, err := cmd.CombinedOutput()
+		require.NoError(t, err, string(out))
+	}
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	for _, content := range []string{"v2", "v3"} {
+		require.NoError(t, os.WriteFile(filepath.Join(root, "a", "testdata", "one.golden"), []byte(content), 0644))
+		git("commit", "-q", "-am", content)
+	}
+
+	manifests := t.TempDir()
+	manifest := `{"package": "example.com/m/a", "dir": ` + strconv.Quote(filepath.Join(root, "a")) + `, "fixtures": [
+		{"test": "TestA", "name": "one", "path": "testdata/one.golden", "size": 2, "outcome": "pass"},
+		{"test": "TestB", "name": "one", "path": "testdata/one.golden", "size": 2, "outcome": "fail"}
+	]}`
+	require.NoError(t, os.WriteFile(filepath.Join(manifests, "example.com_m_a.json"), []byte(manifest), 0644))
+
+	code, stdout, _ := runCommand(t, "-C", root, "churn", "-n", "2", "-manifests", manifests)
+	require.Equal(t, 0, code)
+	assert.Equal(t, ""+
+		"CHANGES  LAST CHANGED  TESTS             FIXTURE\n"+
+		"3        2023-05-01    2 (TestA, TestB)  a/testdata/one.golden\n"+
+		"1        2023-05-01    0                 a/testdata/sub/two.golden\n", stdout)
+
+	code, stdout, _ = runCommand(t, "-C", root, "churn", "-since", "2023-06-01")
+	require.Equal(t, 0, code)
+	assert.Equal(t, "CHANGES  LAST CHANGED  TESTS  FIXTURE\n", stdout)
+}
@@ -0,0 +1,137 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// AccessMode is a combination of the access permissions CheckAccess checks.
+type AccessMode uint32
+
+const (
+	// ReadAccess : permission to read a file or list a directory
+	ReadAccess AccessMode = 4
+	// WriteAccess : permission to write a file or create files in a directory
+	WriteAccess AccessMode = 2
+	// ExecuteAccess : permission to execute a file or search a directory
+	ExecuteAccess AccessMode = 1
+)
+
+// ErrAccessCheckNotSupported is returned by CheckAccessAs on platforms without
+// user and group ids.
+var ErrAccessCheckNotSupported = errors.New("access checks for other users are not supported on this platform")
+
+// String returns the names of the permissions, e.g. "read/write".
+func (m AccessMode) String() string {
+	var names []string
+	if m&ReadAccess != 0 {
+		names = append(names, "read")
+	}
+	if m&WriteAccess != 0 {
+		names = append(names, "write")
+	}
+	if m&ExecuteAccess != 0 {
+		names = append(names, "execute")
+	}
+	if len(names) == 0 {
+		return "no"
+	}
+	return strings.Join(names, "/")
+}
+
+// Credentials are the user and group ids access is checked for.
+type Credentials struct {
+	// UID is the user id.
+	UID uint32
+	// GID is the primary group id.
+	GID uint32
+	// Groups are the supplementary group ids.
+	Groups []uint32
+}
+
+// AccessError explains why access to a file is denied.
+type AccessError struct {
+	// Path is the checked path.
+	Path string
+	// Mode is the requested access.
+	Mode AccessMode
+	// Reason explains why the access is denied.
+	Reason string
+	// Err is the underlying error, e.g. syscall.EACCES or syscall.EROFS.
+	Err error
+}
+
+// Error returns the message of the error.
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("%s access to %s denied: %s", e.Mode, e.Path, e.Reason)
+}
+
+// Unwrap returns the underlying error.
+func (e *AccessError) Unwrap() error {
+	return e.Err
+}
+
+// CheckAccess checks whether the current process can access the path with the
+// mode. Permissions are evaluated like the kernel does for the effective user
+// and group ids and the supplementary groups of the process, including ACLs
+// and read-only mounts on Linux (faccessat(2) with AT_EACCESS). On AIX, which
+// lacks AT_EACCESS, the decision is made like CheckAccessAs does. Symbolic
+// links are followed. The returned error is an *AccessError explaining why access
+// is denied.
+func CheckAccess(path string, mode AccessMode) error {
+	return checkAccess(path, mode)
+}
+
+// CheckAccessAs checks whether a process with the credentials could access the
+// path with the mode. The decision is made from the permission bits, the owner
+// and the group of the file and from read-only mounts; ACLs and security
+// modules are not taken into account. The returned error is an *AccessError
+// explaining why access is denied.
+func CheckAccessAs(path string, mode AccessMode, cred Credentials) error {
+	return checkAccessAs(path, mode, cred)
+}
+
+// permitted evaluates the permission bits of a file like POSIX does: the owner
+// bits apply to the owner, the group bits to members of the group and the
+// other bits to everybody else. The super user may read and write anything
+// and execute files with at least one execute bit. The reason is empty if the
+// access is permitted.
+func permitted(info os.FileInfo, owner uint32, group uint32, mode AccessMode, cred Credentials) string {
+	perm := info.Mode().Perm()
+
+	if cred.UID == 0 {
+		if mode&ExecuteAccess != 0 && !info.IsDir() && perm&0111 == 0 {
+			return fmt.Sprintf("mode %s has no execute bit, not even the super user may execute it", perm)
+		}
+		return ""
+	}
+
+	class, bits := "other", AccessMode(perm&7)
+	switch {
+	case cred.UID == owner:
+		class, bits = "owner", AccessMode(perm>>6&7)
+	case cred.inGroup(group):
+		class, bits = "group", AccessMode(perm>>3&7)
+	}
+
+	if missing := mode &^ bits; missing != 0 {
+		return fmt.Sprintf("uid %d is in the %s class of mode %s (owner %d, group %d), which lacks %s permission",
+			cred.UID, class, perm, owner, group, missing)
+	}
+	return ""
+}
+
+// inGroup reports whether the credentials include the group.
+func (c Credentials) inGroup(group uint32) bool {
+	if c.GID == group {
+		return true
+	}
+	for _, g := range c.Groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
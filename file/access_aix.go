@@ -0,0 +1,7 @@
+package file
+
+// checkAccess evaluates the permission bits for the credentials of the
+// process, AIX has no faccessat(2) with AT_EACCESS.
+func checkAccess(path string, mode AccessMode) error {
+	return checkAccessAs(path, mode, CurrentCredentials())
+}
@@ -0,0 +1,15 @@
+//go:build darwin || dragonfly || freebsd
+
+package file
+
+import "golang.org/x/sys/unix"
+
+// isReadOnlyMount reports whether the path is on a file system mounted
+// read-only.
+func isReadOnlyMount(path string) bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return false
+	}
+	return uint64(st.Flags)&unix.MNT_RDONLY != 0
+}
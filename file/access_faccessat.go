@@ -0,0 +1,32 @@
+//go:build unix && !aix
+
+package file
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+func checkAccess(path string, mode AccessMode) error {
+	err := unix.Faccessat(unix.AT_FDCWD, path, uint32(mode), unix.AT_EACCESS)
+	if err == nil {
+		return nil
+	}
+
+	accessErr := &AccessError{Path: path, Mode: mode, Reason: err.Error(), Err: err}
+	switch {
+	case errors.Is(err, unix.EROFS):
+		accessErr.Reason = "the file system is mounted read-only"
+	case errors.Is(err, unix.ETXTBSY):
+		accessErr.Reason = "the file is being executed"
+	case errors.Is(err, unix.EACCES) || errors.Is(err, unix.EPERM):
+		// explain the denial with the permission bits if they are the cause
+		accessErr.Reason = "denied by an ACL or a security module"
+		var explained *AccessError
+		if errors.As(checkAccessAs(path, mode, CurrentCredentials()), &explained) {
+			accessErr.Reason = explained.Reason
+		}
+	}
+	return accessErr
+}
@@ -0,0 +1,13 @@
+package file
+
+import "golang.org/x/sys/unix"
+
+// isReadOnlyMount reports whether the path is on a file system mounted
+// read-only.
+func isReadOnlyMount(path string) bool {
+	var st unix.Statfs_t
+	if err := unix.Statfs(path, &st); err != nil {
+		return false
+	}
+	return st.Flags&unix.ST_RDONLY != 0
+}
@@ -0,0 +1,29 @@
+//go:build !unix && !windows
+
+package file
+
+import "os"
+
+// CurrentCredentials returns zero credentials, the platform has no user and
+// group ids.
+func CurrentCredentials() Credentials {
+	return Credentials{}
+}
+
+// checkAccess falls back to the owner permission bits on platforms without
+// access checks.
+func checkAccess(path string, mode AccessMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &AccessError{Path: path, Mode: mode, Reason: err.Error(), Err: err}
+	}
+
+	if missing := mode &^ AccessMode(info.Mode().Perm()>>6&7); missing != 0 {
+		return &AccessError{Path: path, Mode: mode, Reason: "mode " + info.Mode().Perm().String() + " lacks " + missing.String() + " permission", Err: os.ErrPermission}
+	}
+	return nil
+}
+
+func checkAccessAs(path string, mode AccessMode, _ Credentials) error {
+	return &AccessError{Path: path, Mode: mode, Reason: ErrAccessCheckNotSupported.Error(), Err: ErrAccessCheckNotSupported}
+}
@@ -0,0 +1,79 @@
+//go:build unix
+
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessModeString(t *testing.T) {
+	assert.Equal(t, "read", ReadAccess.String())
+	assert.Equal(t, "read/write/execute", (ReadAccess | WriteAccess | ExecuteAccess).String())
+	assert.Equal(t, "no", AccessMode(0).String())
+}
+
+func TestCheckAccess(t *testing.T) {
+	assert.NoError(t, CheckAccess("./testdata/Readable.txt", ReadAccess))
+	assert.NoError(t, CheckAccess("./testdata", ReadAccess|ExecuteAccess))
+
+	err := CheckAccess("abcdef", ReadAccess)
+	var accessErr *AccessError
+	require.True(t, errors.As(err, &accessErr))
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+	assert.Equal(t, "abcdef", accessErr.Path)
+
+	err = CheckAccess("./testdata/NonExecutable.txt", ExecuteAccess)
+	require.True(t, errors.As(err, &accessErr))
+	assert.True(t, errors.Is(err, syscall.EACCES))
+	assert.Contains(t, err.Error(), "execute access to ./testdata/NonExecutable.txt denied: ")
+}
+
+func TestCheckAccessAs(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, nil, 0640))
+	require.NoError(t, os.Chmod(path, 0640))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	owner, group := uint32(stat.Uid), uint32(stat.Gid)
+	other := owner + 1000
+
+	// the owner class
+	assert.NoError(t, CheckAccessAs(path, ReadAccess|WriteAccess, Credentials{UID: owner, GID: other}))
+	// the group class, by primary or supplementary group
+	assert.NoError(t, CheckAccessAs(path, ReadAccess, Credentials{UID: other, GID: group}))
+	assert.NoError(t, CheckAccessAs(path, ReadAccess, Credentials{UID: other, GID: other, Groups: []uint32{group}}))
+
+	err = CheckAccessAs(path, WriteAccess, Credentials{UID: other, GID: group})
+	assert.True(t, errors.Is(err, syscall.EACCES))
+	assert.Contains(t, err.Error(), "which lacks write permission")
+	assert.Contains(t, err.Error(), "is in the group class of mode -rw-r-----")
+
+	err = CheckAccessAs(path, ReadAccess, Credentials{UID: other, GID: other})
+	assert.Contains(t, err.Error(), "is in the other class")
+
+	// the super user may read and write, but needs an execute bit
+	assert.NoError(t, CheckAccessAs(path, ReadAccess|WriteAccess, Credentials{}))
+	err = CheckAccessAs(path, ExecuteAccess, Credentials{})
+	assert.Contains(t, err.Error(), "has no execute bit")
+	assert.NoError(t, CheckAccessAs(dir, ExecuteAccess, Credentials{}))
+
+	err = CheckAccessAs(filepath.Join(dir, "missing"), ReadAccess, Credentials{})
+	assert.True(t, errors.Is(err, fs.ErrNotExist))
+}
+
+func TestCurrentCredentials(t *testing.T) {
+	cred := CurrentCredentials()
+	assert.Equal(t, uint32(os.Geteuid()), cred.UID)
+	assert.Equal(t, uint32(os.Getegid()), cred.GID)
+}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// CurrentCredentials returns the effective user and group ids and the
+// supplementary groups of the current process.
+func CurrentCredentials() Credentials {
+	cred := Credentials{UID: uint32(os.Geteuid()), GID: uint32(os.Getegid())}
+	groups, _ := os.Getgroups()
+	for _, g := range groups {
+		cred.Groups = append(cred.Groups, uint32(g))
+	}
+	return cred
+}
+
+func checkAccessAs(path string, mode AccessMode, cred Credentials) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &AccessError{Path: path, Mode: mode, Reason: err.Error(), Err: err}
+	}
+
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return &AccessError{Path: path, Mode: mode, Reason: "the owner of the file is unknown", Err: ErrAccessCheckNotSupported}
+	}
+
+	if mode&WriteAccess != 0 && isReadOnlyMount(path) {
+		return &AccessError{Path: path, Mode: mode, Reason: "the file system is mounted read-only", Err: unix.EROFS}
+	}
+
+	if reason := permitted(info, uint32(stat.Uid), uint32(stat.Gid), mode, cred); reason != "" {
+		return &AccessError{Path: path, Mode: mode, Reason: reason, Err: unix.EACCES}
+	}
+	return nil
+}
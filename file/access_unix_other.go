@@ -0,0 +1,10 @@
+//go:build unix && !linux && !darwin && !dragonfly && !freebsd && !openbsd
+
+package file
+
+// isReadOnlyMount reports whether the path is on a file system mounted
+// read-only. The mount flags are not available on this platform, write
+// access is denied by the kernel instead.
+func isReadOnlyMount(string) bool {
+	return false
+}
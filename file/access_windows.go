@@ -0,0 +1,63 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CurrentCredentials returns zero credentials, Windows has no user and group
+// ids.
+func CurrentCredentials() Credentials {
+	return Credentials{}
+}
+
+func checkAccess(path string, mode AccessMode) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return &AccessError{Path: path, Mode: mode, Reason: err.Error(), Err: err}
+	}
+
+	if mode&ReadAccess != 0 {
+		f, err := os.Open(path)
+		if err != nil {
+			return &AccessError{Path: path, Mode: mode, Reason: err.Error(), Err: err}
+		}
+		f.Close()
+	}
+	if mode&WriteAccess != 0 && info.Mode().Perm()&0200 == 0 {
+		return &AccessError{Path: path, Mode: mode, Reason: "the file is read-only", Err: os.ErrPermission}
+	}
+	if mode&ExecuteAccess != 0 && !info.IsDir() && !isExecutableExt(path) && !isPEFile(path) {
+		return &AccessError{Path: path, Mode: mode, Reason: "the file is no PE executable and its extension is not listed in PATHEXT", Err: os.ErrPermission}
+	}
+	return nil
+}
+
+func checkAccessAs(path string, mode AccessMode, _ Credentials) error {
+	return &AccessError{Path: path, Mode: mode, Reason: ErrAccessCheckNotSupported.Error(), Err: ErrAccessCheckNotSupported}
+}
+
+// isExecutableExt reports whether the extension of the path is one of the
+// executable extensions listed in PATHEXT.
+func isExecutableExt(path string) bool {
+	exts := os.Getenv("PATHEXT")
+	if exts == "" {
+		exts = ".com;.exe;.bat;.cmd"
+	}
+
+	ext := filepath.Ext(path)
+	for _, e := range filepath.SplitList(exts) {
+		if e != "" && strings.EqualFold(e, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPEFile reports whether the content of the file is a PE executable, which
+// can be run regardless of its extension.
+func isPEFile(path string) bool {
+	t, err := DetectType(path)
+	return err == nil && t.MIME == peMIME
+}
@@ -0,0 +1,18 @@
+//go:build darwin || freebsd || netbsd
+
+package file
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the access time of the file.
+func accessTime(info os.FileInfo) time.Time {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(int64(stat.Atimespec.Sec), int64(stat.Atimespec.Nsec))
+}
@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package file
+
+import (
+	"os"
+	"time"
+)
+
+// accessTime returns the modification time, the access time is not read on
+// this platform.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
@@ -0,0 +1,16 @@
+package file
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// accessTime returns the access time of the file.
+func accessTime(info os.FileInfo) time.Time {
+	attrs, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(0, attrs.LastAccessTime.Nanoseconds())
+}
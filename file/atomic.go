@@ -0,0 +1,182 @@
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// ErrAtomicWriterClosed is returned by an AtomicWriter after Commit or Abort.
+var ErrAtomicWriterClosed = errors.New("atomic writer is already committed or aborted")
+
+// tempCounter makes the names of temporary files unique within the process.
+var tempCounter uint32
+
+// AtomicWriter writes a file through a temporary file in the same directory
+// that replaces the file on Commit. Readers see either the old or the complete
+// new content, never a partially written file. Close without Commit discards
+// the written content, so `defer w.Close()` is safe after Commit.
+type AtomicWriter struct {
+	path string
+	tmp  *os.File
+	done bool
+}
+
+// NewAtomicWriter starts writing the file at path. If the file exists, its
+// mode is kept, otherwise it's created with perm minus the umask. If path is a
+// symbolic link, the file it points to is replaced, or created if the link is
+// dangling.
+func NewAtomicWriter(path string, perm os.FileMode) (*AtomicWriter, error) {
+	path, err := resolveSymlinks(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var mode os.FileMode
+	info, err := os.Stat(path)
+	switch {
+	case err == nil:
+		if !info.Mode().IsRegular() {
+			return nil, &os.PathError{Op: "write", Path: path, Err: errors.New("not a regular file")}
+		}
+		mode = info.Mode() & (os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky)
+	case !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	}
+
+	tmp, err := createTemp(path, perm)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil {
+		if err := tmp.Chmod(mode); err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return nil, err
+		}
+	}
+	return &AtomicWriter{path: path, tmp: tmp}, nil
+}
+
+// maxSymlinks limits the number of symbolic links followed by resolveSymlinks.
+const maxSymlinks = 255
+
+// resolveSymlinks returns the file path refers to. Unlike
+// filepath.EvalSymlinks, it also follows dangling links, so that their target
+// is created instead of the link being replaced.
+func resolveSymlinks(path string) (string, error) {
+	for i := 0; i < maxSymlinks; i++ {
+		resolved, err := filepath.EvalSymlinks(path)
+		if err == nil {
+			return resolved, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", err
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			// not a link: the file itself doesn't exist yet
+			return path, nil
+		}
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		path = target
+	}
+	return "", &os.PathError{Op: "write", Path: path, Err: errors.New("too many levels of symbolic links")}
+}
+
+// createTemp creates a new temporary file next to path. Unlike os.CreateTemp,
+// the umask applies to perm.
+func createTemp(path string, perm os.FileMode) (*os.File, error) {
+	dir, base := filepath.Split(path)
+	for {
+		suffix := strconv.FormatInt(time.Now().UnixNano(), 36) + strconv.FormatUint(uint64(atomic.AddUint32(&tempCounter, 1)), 36)
+		name := filepath.Join(dir, "."+base+".tmp-"+suffix)
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+		if !errors.Is(err, fs.ErrExist) {
+			return f, err
+		}
+	}
+}
+
+// Name returns the path of the file that is written.
+func (w *AtomicWriter) Name() string {
+	return w.path
+}
+
+// Write writes to the temporary file.
+func (w *AtomicWriter) Write(p []byte) (int, error) {
+	if w.done {
+		return 0, ErrAtomicWriterClosed
+	}
+	return w.tmp.Write(p)
+}
+
+// Commit flushes the written content to disk and replaces the file with it.
+// The directory is synced too, so the new file survives a crash. If writing
+// or renaming fails, the file is left unchanged. If only syncing the directory
+// fails, the file is already replaced, but the replacement may be lost on a
+// crash; the error is then the *os.PathError of the sync.
+func (w *AtomicWriter) Commit() error {
+	if w.done {
+		return ErrAtomicWriterClosed
+	}
+	w.done = true
+
+	err := w.tmp.Sync()
+	if closeErr := w.tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(w.tmp.Name(), w.path)
+	}
+	if err != nil {
+		_ = os.Remove(w.tmp.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(w.path))
+}
+
+// Abort discards the written content and leaves the file unchanged.
+func (w *AtomicWriter) Abort() error {
+	if w.done {
+		return ErrAtomicWriterClosed
+	}
+	w.done = true
+
+	err := w.tmp.Close()
+	if removeErr := os.Remove(w.tmp.Name()); err == nil {
+		err = removeErr
+	}
+	return err
+}
+
+// Close aborts the write unless it's committed or aborted already.
+func (w *AtomicWriter) Close() error {
+	if w.done {
+		return nil
+	}
+	return w.Abort()
+}
+
+// WriteFileAtomic writes data to the file at path like os.WriteFile, but
+// atomically: readers see either the old or the complete new content. The
+// mode of an existing file is kept.
+func WriteFileAtomic(path string, data []byte, perm os.FileMode) error {
+	w, err := NewAtomicWriter(path, perm)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Commit()
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package file
+
+import "os"
+
+// syncDir flushes the entries of the directory, e.g. a renamed file, to disk.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,125 @@
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// assertNoTempFiles checks that only the expected files are in the directory.
+func assertNoTempFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var got []string
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	assert.Equal(t, names, got)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.json")
+
+	require.NoError(t, WriteFileAtomic(path, []byte("first"), 0600))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "first", string(data))
+
+	require.NoError(t, WriteFileAtomic(path, []byte("second"), 0644))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+	assertNoTempFiles(t, dir, "config.json")
+
+	err = WriteFileAtomic(filepath.Join(dir, "missing", "file"), nil, 0600)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	err = WriteFileAtomic(dir, nil, 0600)
+	assert.EqualError(t, err, "write "+dir+": not a regular file")
+}
+
+func TestWriteFileAtomicKeepsMode(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows has no permission bits")
+	}
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "script.sh")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0751))
+	require.NoError(t, os.Chmod(path, 0751))
+
+	require.NoError(t, WriteFileAtomic(path, []byte("new"), 0600))
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0751), info.Mode().Perm())
+
+	// the file a symbolic link points to is replaced
+	link := filepath.Join(dir, "link")
+	require.NoError(t, os.Symlink("script.sh", link))
+	require.NoError(t, WriteFileAtomic(link, []byte("through link"), 0600))
+	assert.True(t, IsSymlink(link))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "through link", string(data))
+
+	// the target of a dangling link is created
+	dangling := filepath.Join(dir, "dangling")
+	require.NoError(t, os.Symlink("created.txt", dangling))
+	require.NoError(t, WriteFileAtomic(dangling, []byte("created"), 0600))
+	assert.True(t, IsSymlink(dangling))
+	data, err = os.ReadFile(filepath.Join(dir, "created.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "created", string(data))
+
+	loop := filepath.Join(dir, "loop")
+	require.NoError(t, os.Symlink("loop", loop))
+	assert.Error(t, WriteFileAtomic(loop, nil, 0600))
+}
+
+func TestAtomicWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	require.NoError(t, os.WriteFile(path, []byte("old"), 0600))
+
+	w, err := NewAtomicWriter(path, 0600)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("partial"))
+	require.NoError(t, err)
+
+	// the file is unchanged until the commit
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "old", string(data))
+
+	_, err = w.Write([]byte(" and complete"))
+	require.NoError(t, err)
+	require.NoError(t, w.Commit())
+	require.NoError(t, w.Close())
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "partial and complete", string(data))
+
+	assert.Equal(t, ErrAtomicWriterClosed, w.Commit())
+	_, err = w.Write(nil)
+	assert.Equal(t, ErrAtomicWriterClosed, err)
+
+	// closing without a commit discards the content
+	w, err = NewAtomicWriter(path, 0600)
+	require.NoError(t, err)
+	_, err = w.Write([]byte("discarded"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	assert.Equal(t, ErrAtomicWriterClosed, w.Abort())
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "partial and complete", string(data))
+	assertNoTempFiles(t, dir, "file.txt")
+}
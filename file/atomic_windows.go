@@ -0,0 +1,6 @@
+package file
+
+// syncDir does nothing, directories can't be opened for syncing on Windows.
+func syncDir(string) error {
+	return nil
+}
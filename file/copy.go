@@ -0,0 +1,382 @@
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+)
+
+// SymlinkPolicy decides what Copy does with a source that is a symbolic link.
+type SymlinkPolicy int
+
+const (
+	// SymlinkFollow copies the file the link points to.
+	SymlinkFollow SymlinkPolicy = iota
+	// SymlinkCopy creates a link with the same target at the destination.
+	SymlinkCopy
+	// SymlinkSkip copies nothing.
+	SymlinkSkip
+)
+
+// OverwritePolicy decides what Copy does if the destination already exists.
+type OverwritePolicy int
+
+const (
+	// Overwrite replaces the content of the destination.
+	Overwrite OverwritePolicy = iota
+	// NoClobber keeps the destination and returns ErrDestinationExists.
+	NoClobber
+	// Backup renames the destination by appending the backup suffix (`~` by
+	// default) before copying.
+	Backup
+)
+
+// defaultBackupSuffix is appended to the destination with the Backup policy.
+const defaultBackupSuffix = "~"
+
+// sparseBlockSize is the size of the blocks the sparse copy checks for zeros.
+const sparseBlockSize = 32 * 1024
+
+// ErrDestinationExists is returned by Copy with the NoClobber policy if the
+// destination exists. It matches fs.ErrExist.
+var ErrDestinationExists = fmt.Errorf("destination %w", fs.ErrExist)
+
+// CopyOption configures Copy.
+type CopyOption func(*copyOptions)
+
+// copyOptions are the options of Copy.
+type copyOptions struct {
+	preserveMode      bool
+	preserveTimes     bool
+	preserveOwnership bool
+	preserveXattrs    bool
+	symlinks          SymlinkPolicy
+	overwrite         OverwritePolicy
+	backupSuffix      string
+	sparse            bool
+
+	// the options of CopyDir
+	concurrency int
+	include     []string
+	exclude     []string
+	ignore      []string
+	progress    func(Progress)
+
+	// written is called with the number of bytes copied.
+	written func(int64)
+}
+
+// WithPreserveMode copies the permission bits, including the setuid, setgid
+// and sticky bits, regardless of the umask.
+func WithPreserveMode(preserve bool) CopyOption {
+	return func(o *copyOptions) {
+		o.preserveMode = preserve
+	}
+}
+
+// WithPreserveTimes copies the modification and access time. On Plan 9 and
+// WebAssembly the access time isn't read and the modification time is used
+// instead. The times of copied symbolic links are not preserved.
+func WithPreserveTimes(preserve bool) CopyOption {
+	return func(o *copyOptions) {
+		o.preserveTimes = preserve
+	}
+}
+
+// WithPreserveOwnership copies the owner and group on Unix. Changing the owner
+// usually requires super user privileges.
+func WithPreserveOwnership(preserve bool) CopyOption {
+	return func(o *copyOptions) {
+		o.preserveOwnership = preserve
+	}
+}
+
+// WithPreserveXattrs copies the extended attributes on Linux, macOS, FreeBSD
+// and NetBSD. Attributes outside the `user.` namespace that may not be set
+// are skipped. Other platforms have no extended attributes this package
+// supports, there the option does nothing.
+func WithPreserveXattrs(preserve bool) CopyOption {
+	return func(o *copyOptions) {
+		o.preserveXattrs = preserve
+	}
+}
+
+// WithSymlinkPolicy sets what is done with a source that is a symbolic link.
+// The default is SymlinkFollow.
+func WithSymlinkPolicy(policy SymlinkPolicy) CopyOption {
+	return func(o *copyOptions) {
+		o.symlinks = policy
+	}
+}
+
+// WithOverwritePolicy sets what is done if the destination exists. The
+// default is Overwrite.
+func WithOverwritePolicy(policy OverwritePolicy) CopyOption {
+	return func(o *copyOptions) {
+		o.overwrite = policy
+	}
+}
+
+// WithBackupSuffix sets the suffix appended to the destination with the
+// Backup policy.
+func WithBackupSuffix(suffix string) CopyOption {
+	return func(o *copyOptions) {
+		o.backupSuffix = suffix
+	}
+}
+
+// WithSparse skips writing blocks of zeros, so holes in the source stay holes
+// in the destination instead of taking disk space.
+func WithSparse(sparse bool) CopyOption {
+	return func(o *copyOptions) {
+		o.sparse = sparse
+	}
+}
+
+// WithArchive preserves everything and copies symbolic links as links, like
+// `cp -a`. The limits of WithPreserveTimes, WithPreserveOwnership and
+// WithPreserveXattrs on some platforms apply.
+func WithArchive() CopyOption {
+	return func(o *copyOptions) {
+		o.preserveMode = true
+		o.preserveTimes = true
+		o.preserveOwnership = true
+		o.preserveXattrs = true
+		o.symlinks = SymlinkCopy
+		o.sparse = true
+	}
+}
+
+// newCopyOptions applies the options to the defaults.
+func newCopyOptions(opts []CopyOption) *copyOptions {
+	o := &copyOptions{backupSuffix: defaultBackupSuffix}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// Copy copy file to destination path. A new destination gets the permission
+// bits of the source minus the umask, an existing one keeps its permissions
+// unless WithPreserveMode is used. The options select which metadata is
+// preserved and how symbolic links and existing destinations are handled.
+func Copy(src string, dest string, opts ...CopyOption) error {
+	o := newCopyOptions(opts)
+
+	info, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+
+	link := info.Mode()&os.ModeSymlink != 0
+	if link {
+		switch o.symlinks {
+		case SymlinkSkip:
+			return nil
+		case SymlinkFollow:
+			if info, err = os.Stat(src); err != nil {
+				return err
+			}
+			link = false
+		}
+	}
+
+	return copyFile(src, info, link, dest, o)
+}
+
+// copyFile copies a regular file, or a link if link is set, whose source
+// information is known.
+func copyFile(src string, info os.FileInfo, link bool, dest string, o *copyOptions) error {
+	if !link && !info.Mode().IsRegular() {
+		return &os.PathError{Op: "copy", Path: src, Err: errors.New("not a regular file")}
+	}
+
+	exists, err := prepareDest(src, info, dest, o)
+	if err != nil {
+		return err
+	}
+
+	if link {
+		err = copySymlink(src, dest, exists)
+	} else {
+		err = copyContents(src, dest, info.Mode().Perm(), o)
+	}
+	if link && o.overwrite == NoClobber && errors.Is(err, fs.ErrExist) {
+		// the destination was created after prepareDest checked it
+		err = &os.PathError{Op: "copy", Path: dest, Err: ErrDestinationExists}
+	}
+	if err != nil {
+		return err
+	}
+
+	return preserveMetadata(src, info, dest, link, o)
+}
+
+// prepareDest applies the overwrite policy to an existing destination and
+// reports whether the destination still exists.
+func prepareDest(src string, info os.FileInfo, dest string, o *copyOptions) (bool, error) {
+	destInfo, err := os.Lstat(dest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if target, err := os.Stat(dest); err == nil && os.SameFile(info, target) {
+		return true, &os.PathError{Op: "copy", Path: dest, Err: fmt.Errorf("%s and %s are the same file", src, dest)}
+	}
+	if destInfo.IsDir() {
+		return true, &os.PathError{Op: "copy", Path: dest, Err: errors.New("is a directory")}
+	}
+
+	switch o.overwrite {
+	case NoClobber:
+		return true, &os.PathError{Op: "copy", Path: dest, Err: ErrDestinationExists}
+	case Backup:
+		return false, os.Rename(dest, dest+o.backupSuffix)
+	}
+	return true, nil
+}
+
+// copySymlink creates a link with the target of the source link.
+func copySymlink(src string, dest string, exists bool) error {
+	target, err := os.Readlink(src)
+	if err != nil {
+		return err
+	}
+	if exists {
+		if err := os.Remove(dest); err != nil {
+			return err
+		}
+	}
+	return os.Symlink(target, dest)
+}
+
+// copyContents copies the content of a regular file. The written callback of
+// the options is called with the number of bytes of every chunk that is
+// copied, if it's set. With the NoClobber policy the destination must not
+// exist when it's opened, so a file created concurrently is never truncated.
+func copyContents(src string, dest string, perm os.FileMode, o *copyOptions) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var s io.Reader = f
+	if o.written != nil {
+		s = &countingReader{r: f, written: o.written}
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if o.overwrite == NoClobber {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+	d, err := os.OpenFile(dest, flag, perm)
+	if o.overwrite == NoClobber && errors.Is(err, fs.ErrExist) {
+		// the destination was created after prepareDest checked it
+		return &os.PathError{Op: "copy", Path: dest, Err: ErrDestinationExists}
+	}
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	if o.sparse {
+		err = copySparse(d, s)
+	} else {
+		_, err = io.Copy(d, s)
+	}
+	if err != nil {
+		return err
+	}
+	return d.Close()
+}
+
+// countingReader reports the number of bytes read.
+type countingReader struct {
+	r       io.Reader
+	written func(int64)
+}
+
+// Read reads from the underlying reader.
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if n > 0 {
+		c.written(int64(n))
+	}
+	return n, err
+}
+
+// copySparse copies the content, seeking over blocks of zeros instead of
+// writing them.
+func copySparse(d *os.File, s io.Reader) error {
+	buf := make([]byte, sparseBlockSize)
+	var size int64
+	for {
+		n, err := io.ReadFull(s, buf)
+		if n > 0 {
+			var writeErr error
+			if block := buf[:n]; isZero(block) {
+				_, writeErr = d.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, writeErr = d.Write(block)
+			}
+			if writeErr != nil {
+				return writeErr
+			}
+			size += int64(n)
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// a trailing hole is only created by setting the size
+			return d.Truncate(size)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// isZero reports whether all bytes of the block are zero.
+func isZero(block []byte) bool {
+	return len(bytes.Trim(block, "\x00")) == 0
+}
+
+// preserveMetadata copies the metadata selected by the options. The owner is
+// set before the mode, because changing it clears the setuid and setgid bits,
+// and the times last, because the other changes may update them.
+func preserveMetadata(src string, info os.FileInfo, dest string, link bool, o *copyOptions) error {
+	if o.preserveOwnership {
+		if err := copyOwnership(info, dest, link); err != nil {
+			return err
+		}
+	}
+
+	if o.preserveXattrs {
+		if err := copyXattrs(src, dest, link); err != nil {
+			return err
+		}
+	}
+
+	if link {
+		return nil
+	}
+
+	if o.preserveMode {
+		if err := os.Chmod(dest, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+	}
+
+	if o.preserveTimes {
+		if err := os.Chtimes(dest, accessTime(info), info.ModTime()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
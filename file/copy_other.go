@@ -0,0 +1,10 @@
+//go:build !unix
+
+package file
+
+import "os"
+
+// copyOwnership does nothing, the platform has no user and group ids.
+func copyOwnership(os.FileInfo, string, bool) error {
+	return nil
+}
@@ -0,0 +1,161 @@
+//go:build unix
+
+package file
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestFile(t *testing.T, path string, content string, perm os.FileMode) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), perm))
+	require.NoError(t, os.Chmod(path, perm))
+}
+
+func TestCopyPreserve(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	writeTestFile(t, src, "content", 0751)
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	atime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(src, atime, mtime))
+
+	// without options the umask applies and the times are new
+	require.NoError(t, Copy(src, dest))
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	assert.NotEqual(t, mtime, info.ModTime().UTC())
+
+	// reading the source updates its access time
+	require.NoError(t, os.Chtimes(src, atime, mtime))
+	require.NoError(t, Copy(src, dest+"2", WithPreserveMode(true), WithPreserveTimes(true)))
+	info, err = os.Stat(dest + "2")
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0751), info.Mode().Perm())
+	assert.Equal(t, mtime, info.ModTime().UTC())
+	assert.Equal(t, atime, accessTime(info).UTC())
+
+	data, err := os.ReadFile(dest + "2")
+	require.NoError(t, err)
+	assert.Equal(t, "content", string(data))
+
+	err = Copy(dir, dest+"3")
+	assert.EqualError(t, err, "copy "+dir+": not a regular file")
+
+	err = Copy(src, src)
+	assert.Contains(t, err.Error(), "are the same file")
+}
+
+func TestCopyOwnership(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("changing the owner requires super user privileges")
+	}
+
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	writeTestFile(t, src, "content", 0644)
+	require.NoError(t, os.Chown(src, 1234, 5678))
+
+	require.NoError(t, Copy(src, dest, WithPreserveOwnership(true)))
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	stat := info.Sys().(*syscall.Stat_t)
+	assert.Equal(t, uint32(1234), stat.Uid)
+	assert.Equal(t, uint32(5678), stat.Gid)
+}
+
+func TestCopySymlinkPolicy(t *testing.T) {
+	dir := t.TempDir()
+	src, link := filepath.Join(dir, "src"), filepath.Join(dir, "link")
+	writeTestFile(t, src, "content", 0644)
+	require.NoError(t, os.Symlink("src", link))
+
+	require.NoError(t, Copy(link, filepath.Join(dir, "followed")))
+	assert.True(t, IsFile(filepath.Join(dir, "followed")))
+	assert.False(t, IsSymlink(filepath.Join(dir, "followed")))
+
+	require.NoError(t, Copy(link, filepath.Join(dir, "copied"), WithSymlinkPolicy(SymlinkCopy)))
+	target, err := os.Readlink(filepath.Join(dir, "copied"))
+	require.NoError(t, err)
+	assert.Equal(t, "src", target)
+
+	// an existing destination is replaced by the link
+	writeTestFile(t, filepath.Join(dir, "existing"), "old", 0644)
+	require.NoError(t, Copy(link, filepath.Join(dir, "existing"), WithArchive()))
+	assert.True(t, IsSymlink(filepath.Join(dir, "existing")))
+
+	require.NoError(t, Copy(link, filepath.Join(dir, "skipped"), WithSymlinkPolicy(SymlinkSkip)))
+	assert.False(t, Exists(filepath.Join(dir, "skipped")))
+}
+
+func TestCopyOverwritePolicy(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	writeTestFile(t, src, "new", 0644)
+	writeTestFile(t, dest, "old content", 0600)
+
+	err := Copy(src, dest, WithOverwritePolicy(NoClobber))
+	assert.True(t, errors.Is(err, ErrDestinationExists))
+	assert.True(t, errors.Is(err, fs.ErrExist))
+
+	require.NoError(t, Copy(src, dest, WithOverwritePolicy(Backup), WithBackupSuffix(".bak")))
+	backup, err := os.ReadFile(dest + ".bak")
+	require.NoError(t, err)
+	assert.Equal(t, "old content", string(backup))
+
+	writeTestFile(t, dest, "old content", 0600)
+	require.NoError(t, Copy(src, dest))
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(data))
+
+	// an overwritten destination keeps its permissions
+	info, err := os.Stat(dest)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestCopyNoClobberRace(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	writeTestFile(t, src, "new", 0644)
+
+	o := newCopyOptions([]CopyOption{WithOverwritePolicy(NoClobber)})
+	info, err := os.Stat(src)
+	require.NoError(t, err)
+	exists, err := prepareDest(src, info, dest, o)
+	require.NoError(t, err)
+	require.False(t, exists)
+
+	// the destination appears between the check and the copy
+	writeTestFile(t, dest, "concurrent", 0644)
+	err = copyContents(src, dest, info.Mode().Perm(), o)
+	assert.True(t, errors.Is(err, ErrDestinationExists))
+
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, "concurrent", string(data))
+}
+
+func TestCopySparse(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+
+	content := make([]byte, 4*sparseBlockSize+10)
+	copy(content[sparseBlockSize:], "data")
+	require.NoError(t, os.WriteFile(src, content, 0644))
+
+	require.NoError(t, Copy(src, dest, WithSparse(true)))
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
@@ -0,0 +1,20 @@
+//go:build unix
+
+package file
+
+import (
+	"os"
+	"syscall"
+)
+
+// copyOwnership sets the owner and group of the source on the destination.
+func copyOwnership(info os.FileInfo, dest string, link bool) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return nil
+	}
+	if link {
+		return os.Lchown(dest, int(stat.Uid), int(stat.Gid))
+	}
+	return os.Chown(dest, int(stat.Uid), int(stat.Gid))
+}
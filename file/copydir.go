@@ -0,0 +1,371 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// ErrSymlinkLoop is returned by CopyDir if a followed symbolic link points to
+// one of the directories containing it.
+var ErrSymlinkLoop = errors.New("symbolic link loop")
+
+// Progress is reported by CopyDir while it copies files.
+type Progress struct {
+	// Path is the path of the file, relative to the source directory, that was
+	// copied last.
+	Path string
+	// Files is the number of files copied.
+	Files int
+	// TotalFiles is the number of files to copy.
+	TotalFiles int
+	// Bytes is the number of bytes copied.
+	Bytes int64
+	// TotalBytes is the size of all files to copy.
+	TotalBytes int64
+}
+
+// WithConcurrency sets the number of files CopyDir copies at the same time.
+// The default is the number of CPUs.
+func WithConcurrency(n int) CopyOption {
+	return func(o *copyOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithInclude makes CopyDir copy only the files matching one of the glob
+// patterns of path.Match. A pattern with a slash is matched against the path
+// relative to the source directory, others against the file name. Directories
+// are always created.
+func WithInclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.include = append(o.include, patterns...)
+	}
+}
+
+// WithExclude makes CopyDir skip the files and directories matching one of
+// the glob patterns, which are matched like the ones of WithInclude.
+func WithExclude(patterns ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.exclude = append(o.exclude, patterns...)
+	}
+}
+
+// WithIgnorePatterns makes CopyDir skip the files and directories ignored by
+// the lines of a gitignore file, e.g. `build/`, `/vendor`, `**/*.log` or
+// `!keep.log`. The patterns are relative to the source directory.
+func WithIgnorePatterns(lines ...string) CopyOption {
+	return func(o *copyOptions) {
+		o.ignore = append(o.ignore, lines...)
+	}
+}
+
+// WithProgress sets a callback CopyDir calls whenever a chunk of a file or a
+// whole file was copied. The calls are serialized.
+func WithProgress(progress func(Progress)) CopyOption {
+	return func(o *copyOptions) {
+		o.progress = progress
+	}
+}
+
+// CopyDir copies the directory tree src to dest. Directories are created with
+// FileModeCreatingDir, or with the mode of the source directory if
+// WithPreserveMode is used, and files are copied concurrently like Copy does.
+// Existing directories are merged, existing files are handled by the overwrite
+// policy. Files other than regular files, directories and symbolic links are
+// skipped. With the SymlinkFollow policy a link pointing to a directory that
+// contains it results in an error wrapping ErrSymlinkLoop. The first error
+// stops the copy.
+func CopyDir(src string, dest string, opts ...CopyOption) error {
+	o := newCopyOptions(opts)
+
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return &os.PathError{Op: "copy", Path: src, Err: errors.New("not a directory")}
+	}
+	if err := checkOutside(src, dest); err != nil {
+		return err
+	}
+
+	w, err := newTreeWalker(o)
+	if err != nil {
+		return err
+	}
+	root := copyTask{src: src, dest: dest, info: info}
+	if err := w.walk(root, []os.FileInfo{info}); err != nil {
+		return err
+	}
+
+	if err := w.makeDirs(root); err != nil {
+		return err
+	}
+	if err := w.copyFiles(); err != nil {
+		return err
+	}
+	return w.preserveDirs(root)
+}
+
+// checkOutside returns an error if dest is inside of src, which would copy the
+// tree into itself.
+func checkOutside(src string, dest string) error {
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return err
+	}
+	absDest, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	rel, err := filepath.Rel(absSrc, absDest)
+	if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return &os.PathError{Op: "copy", Path: dest, Err: fmt.Errorf("cannot copy %s into itself", src)}
+	}
+	return nil
+}
+
+// copyTask is a file, link or directory to copy.
+type copyTask struct {
+	src  string
+	dest string
+	// rel is the slash separated path relative to the source directory.
+	rel  string
+	info os.FileInfo
+	link bool
+}
+
+// treeWalker collects what CopyDir copies.
+type treeWalker struct {
+	o      *copyOptions
+	ignore ignoreMatcher
+
+	dirs       []copyTask
+	files      []copyTask
+	totalBytes int64
+}
+
+// newTreeWalker checks the patterns of the options.
+func newTreeWalker(o *copyOptions) (*treeWalker, error) {
+	for _, pattern := range append(append([]string{}, o.include...), o.exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+	}
+	ignore, err := newIgnoreMatcher(o.ignore)
+	if err != nil {
+		return nil, err
+	}
+	return &treeWalker{o: o, ignore: ignore}, nil
+}
+
+// walk collects the entries of the directory in lexical order. ancestors are
+// the directories containing it, including itself, to detect loops.
+func (w *treeWalker) walk(dir copyTask, ancestors []os.FileInfo) error {
+	entries, err := os.ReadDir(dir.src)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		task := copyTask{
+			src:  filepath.Join(dir.src, entry.Name()),
+			dest: filepath.Join(dir.dest, entry.Name()),
+			rel:  path.Join(dir.rel, entry.Name()),
+		}
+		if task.info, err = os.Lstat(task.src); err != nil {
+			return err
+		}
+
+		if task.info.Mode()&os.ModeSymlink != 0 {
+			switch w.o.symlinks {
+			case SymlinkSkip:
+				continue
+			case SymlinkCopy:
+				task.link = true
+			case SymlinkFollow:
+				if task.info, err = os.Stat(task.src); err != nil {
+					return err
+				}
+			}
+		}
+
+		isDir := !task.link && task.info.IsDir()
+		if w.excluded(task.rel, isDir) {
+			continue
+		}
+
+		switch {
+		case isDir:
+			for _, ancestor := range ancestors {
+				if os.SameFile(ancestor, task.info) {
+					return &os.PathError{Op: "copy", Path: task.src, Err: ErrSymlinkLoop}
+				}
+			}
+			w.dirs = append(w.dirs, task)
+			if err := w.walk(task, append(ancestors, task.info)); err != nil {
+				return err
+			}
+		case task.link || task.info.Mode().IsRegular():
+			if !w.included(task.rel) {
+				continue
+			}
+			w.files = append(w.files, task)
+			if !task.link {
+				w.totalBytes += task.info.Size()
+			}
+		}
+	}
+	return nil
+}
+
+// excluded reports whether the exclude or ignore patterns match the path.
+func (w *treeWalker) excluded(rel string, isDir bool) bool {
+	return matchGlobs(w.o.exclude, rel) || w.ignore.match(rel, isDir)
+}
+
+// included reports whether the file matches the include patterns, if any.
+func (w *treeWalker) included(rel string) bool {
+	return len(w.o.include) == 0 || matchGlobs(w.o.include, rel)
+}
+
+// matchGlobs reports whether one of the patterns matches the relative path or,
+// for patterns without a slash, the file name.
+func matchGlobs(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// makeDirs creates the directories. Preserved modes are applied after the
+// files are copied, so read-only directories can be filled.
+func (w *treeWalker) makeDirs(root copyTask) error {
+	for _, dir := range append([]copyTask{root}, w.dirs...) {
+		perm := FileModeCreatingDir
+		if w.o.preserveMode {
+			perm = dir.info.Mode().Perm() | 0700
+		}
+
+		err := os.Mkdir(dir.dest, perm)
+		if errors.Is(err, os.ErrNotExist) && dir.rel == "" {
+			err = os.MkdirAll(dir.dest, perm)
+		}
+		if errors.Is(err, os.ErrExist) && IsDir(dir.dest) {
+			err = nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyFiles copies the files with a pool of workers.
+func (w *treeWalker) copyFiles() error {
+	workers := w.o.concurrency
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	r := &copyRun{progress: w.o.progress}
+	r.state.TotalFiles = len(w.files)
+	r.state.TotalBytes = w.totalBytes
+
+	tasks := make(chan copyTask)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for task := range tasks {
+				r.copy(task, w.o)
+			}
+		}()
+	}
+
+	for _, task := range w.files {
+		if r.failed() {
+			break
+		}
+		tasks <- task
+	}
+	close(tasks)
+	wg.Wait()
+	return r.err
+}
+
+// preserveDirs applies the preserved metadata to the directories, deepest
+// first, since copying into a directory changes its modification time.
+func (w *treeWalker) preserveDirs(root copyTask) error {
+	dirs := append([]copyTask{root}, w.dirs...)
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := preserveMetadata(dirs[i].src, dirs[i].info, dirs[i].dest, false, w.o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRun is the shared state of the workers of CopyDir.
+type copyRun struct {
+	mu       sync.Mutex
+	err      error
+	state    Progress
+	progress func(Progress)
+}
+
+// copy copies a file and reports the progress.
+func (r *copyRun) copy(task copyTask, o *copyOptions) {
+	if r.failed() {
+		return
+	}
+
+	fileOptions := *o
+	if r.progress != nil {
+		fileOptions.written = func(n int64) {
+			r.update(task.rel, n, 0)
+		}
+	}
+
+	if err := copyFile(task.src, task.info, task.link, task.dest, &fileOptions); err != nil {
+		r.mu.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.mu.Unlock()
+		return
+	}
+	r.update(task.rel, 0, 1)
+}
+
+// update adds the copied bytes and files and calls the progress callback.
+func (r *copyRun) update(rel string, bytes int64, files int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.state.Path = rel
+	r.state.Bytes += bytes
+	r.state.Files += files
+	if r.progress != nil {
+		r.progress(r.state)
+	}
+}
+
+// failed reports whether a worker failed.
+func (r *copyRun) failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err != nil
+}
@@ -0,0 +1,164 @@
+//go:build unix
+
+package file
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeTree creates the files, with their path as content, below the
+// directory.
+func makeTree(t *testing.T, dir string, files ...string) {
+	t.Helper()
+	for _, name := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		writeTestFile(t, path, name, 0644)
+	}
+}
+
+// listTree returns the slash separated paths of the files and links below the
+// directory.
+func listTree(t *testing.T, dir string) []string {
+	t.Helper()
+	var files []string
+	require.NoError(t, filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		files = append(files, filepath.ToSlash(rel))
+		return err
+	}))
+	sort.Strings(files)
+	return files
+}
+
+func TestCopyDir(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "out", "dest")
+	makeTree(t, src, "a.txt", "sub/b.txt", "sub/deep/c.txt")
+	require.NoError(t, os.Mkdir(filepath.Join(src, "empty"), 0755))
+
+	require.NoError(t, CopyDir(src, dest, WithConcurrency(2)))
+	assert.Equal(t, []string{"a.txt", "sub/b.txt", "sub/deep/c.txt"}, listTree(t, dest))
+	assert.True(t, IsDir(filepath.Join(dest, "empty")))
+
+	data, err := os.ReadFile(filepath.Join(dest, "sub", "deep", "c.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "sub/deep/c.txt", string(data))
+
+	info, err := os.Stat(filepath.Join(dest, "sub"))
+	require.NoError(t, err)
+	assert.Equal(t, FileModeCreatingDir, info.Mode().Perm())
+
+	// copying again merges into the existing tree
+	require.NoError(t, CopyDir(src, dest))
+
+	err = CopyDir(filepath.Join(src, "a.txt"), dest)
+	assert.EqualError(t, err, "copy "+filepath.Join(src, "a.txt")+": not a directory")
+
+	err = CopyDir(src, filepath.Join(src, "sub", "copy"))
+	assert.Contains(t, err.Error(), "into itself")
+}
+
+func TestCopyDirPreserve(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	makeTree(t, src, "ro/a.txt")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(filepath.Join(src, "ro"), mtime, mtime))
+	require.NoError(t, os.Chmod(filepath.Join(src, "ro"), 0555))
+	t.Cleanup(func() {
+		_ = os.Chmod(filepath.Join(src, "ro"), 0755)
+		_ = os.Chmod(filepath.Join(dest, "ro"), 0755)
+	})
+
+	require.NoError(t, CopyDir(src, dest, WithPreserveMode(true), WithPreserveTimes(true)))
+	info, err := os.Stat(filepath.Join(dest, "ro"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0555), info.Mode().Perm())
+	assert.Equal(t, mtime, info.ModTime().UTC())
+	assert.True(t, IsFile(filepath.Join(dest, "ro", "a.txt")))
+}
+
+func TestCopyDirFilters(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTree(t, src, "main.go", "main_test.go", "README.md", "build/out.go", "sub/util.go", "sub/debug.log", "sub/keep.log")
+
+	dest := filepath.Join(dir, "include")
+	require.NoError(t, CopyDir(src, dest, WithInclude("*.go"), WithExclude("*_test.go", "build")))
+	assert.Equal(t, []string{"main.go", "sub/util.go"}, listTree(t, dest))
+
+	dest = filepath.Join(dir, "ignore")
+	require.NoError(t, CopyDir(src, dest, WithIgnorePatterns("build/", "*.log", "!keep.log", "/README.md")))
+	assert.Equal(t, []string{"main.go", "main_test.go", "sub/keep.log", "sub/util.go"}, listTree(t, dest))
+	assert.False(t, Exists(filepath.Join(dest, "build")))
+
+	err := CopyDir(src, filepath.Join(dir, "bad"), WithInclude("[a-"))
+	assert.Contains(t, err.Error(), `invalid glob pattern "[a-"`)
+}
+
+func TestCopyDirSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTree(t, src, "sub/a.txt")
+	require.NoError(t, os.Symlink("sub", filepath.Join(src, "linked")))
+
+	dest := filepath.Join(dir, "followed")
+	require.NoError(t, CopyDir(src, dest))
+	assert.Equal(t, []string{"linked/a.txt", "sub/a.txt"}, listTree(t, dest))
+
+	dest = filepath.Join(dir, "copied")
+	require.NoError(t, CopyDir(src, dest, WithSymlinkPolicy(SymlinkCopy)))
+	assert.True(t, IsSymlink(filepath.Join(dest, "linked")))
+
+	dest = filepath.Join(dir, "skipped")
+	require.NoError(t, CopyDir(src, dest, WithSymlinkPolicy(SymlinkSkip)))
+	assert.Equal(t, []string{"sub/a.txt"}, listTree(t, dest))
+
+	// a link to a containing directory can't be followed
+	require.NoError(t, os.Symlink("..", filepath.Join(src, "sub", "loop")))
+	err := CopyDir(src, filepath.Join(dir, "loop"))
+	assert.True(t, errors.Is(err, ErrSymlinkLoop))
+	assert.True(t, strings.HasSuffix(err.Error(), "/loop: symbolic link loop"))
+	require.NoError(t, CopyDir(src, filepath.Join(dir, "loop"), WithSymlinkPolicy(SymlinkCopy)))
+}
+
+func TestCopyDirProgress(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	makeTree(t, src, "a.txt", "b/c.txt")
+	require.NoError(t, os.WriteFile(filepath.Join(src, "big"), []byte(strings.Repeat("x", 100000)), 0644))
+
+	var mu sync.Mutex
+	var reports []Progress
+	require.NoError(t, CopyDir(src, filepath.Join(dir, "dest"), WithProgress(func(p Progress) {
+		mu.Lock()
+		defer mu.Unlock()
+		reports = append(reports, p)
+	})))
+
+	require.NotEmpty(t, reports)
+	last := reports[len(reports)-1]
+	assert.Equal(t, 3, last.Files)
+	assert.Equal(t, 3, last.TotalFiles)
+	assert.Equal(t, int64(100000+len("a.txt")+len("b/c.txt")), last.TotalBytes)
+	assert.Equal(t, last.TotalBytes, last.Bytes)
+
+	for i := 1; i < len(reports); i++ {
+		assert.GreaterOrEqual(t, reports[i].Bytes, reports[i-1].Bytes)
+		assert.GreaterOrEqual(t, reports[i].Files, reports[i-1].Files)
+	}
+}
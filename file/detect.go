@@ -0,0 +1,381 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// sniffLen is the number of bytes DetectType reads from the start of a file.
+const sniffLen = 1024
+
+// Category is the kind of content of a file.
+type Category int
+
+const (
+	// CategoryUnknown : binary content without a known signature
+	CategoryUnknown Category = iota
+	// CategoryText : plain text
+	CategoryText
+	// CategoryScript : text starting with a shebang line
+	CategoryScript
+	// CategoryImage : image formats, e.g. PNG or JPEG
+	CategoryImage
+	// CategoryAudio : audio formats, e.g. MP3 or FLAC
+	CategoryAudio
+	// CategoryVideo : video formats, e.g. MP4
+	CategoryVideo
+	// CategoryArchive : archives and compressed files, e.g. zip or gzip
+	CategoryArchive
+	// CategoryDocument : documents, e.g. PDF
+	CategoryDocument
+	// CategoryExecutable : native executables and libraries (ELF, Mach-O, PE)
+	CategoryExecutable
+)
+
+// String returns the name of the category.
+func (c Category) String() string {
+	switch c {
+	case CategoryText:
+		return "text"
+	case CategoryScript:
+		return "script"
+	case CategoryImage:
+		return "image"
+	case CategoryAudio:
+		return "audio"
+	case CategoryVideo:
+		return "video"
+	case CategoryArchive:
+		return "archive"
+	case CategoryDocument:
+		return "document"
+	case CategoryExecutable:
+		return "executable"
+	default:
+		return "unknown"
+	}
+}
+
+// FileType is the type of a file detected from its content.
+type FileType struct {
+	// MIME is the media type without parameters, e.g. "image/png".
+	// Unknown binary content is "application/octet-stream".
+	MIME string
+	// Category is the kind of content.
+	Category Category
+	// Encoding is the character encoding of text and scripts: "us-ascii",
+	// "utf-8", "utf-16le" or "utf-16be".
+	Encoding string
+	// Interpreter is the command of the shebang line of a script, e.g.
+	// "python3" for `#!/usr/bin/env python3`.
+	Interpreter string
+}
+
+// IsExecutable reports whether the content is a native executable or a script.
+func (t FileType) IsExecutable() bool {
+	return t.Category == CategoryExecutable || t.Category == CategoryScript
+}
+
+// Signature identifies a file type by a magic number.
+type Signature struct {
+	// Offset is the position of Magic in the file.
+	Offset int
+	// Magic are the bytes the file must contain at Offset.
+	Magic []byte
+	// Match optionally checks the first bytes of the file further if Magic
+	// matches. It may be used without Magic.
+	Match func(header []byte) bool
+	// MIME is the media type of the files.
+	MIME string
+	// Category is the kind of content of the files.
+	Category Category
+}
+
+// matches reports whether the header of a file matches the signature.
+func (s Signature) matches(header []byte) bool {
+	if len(s.Magic) > 0 {
+		if len(header) < s.Offset+len(s.Magic) || !bytes.Equal(header[s.Offset:s.Offset+len(s.Magic)], s.Magic) {
+			return false
+		}
+	}
+	return s.Match == nil || s.Match(header)
+}
+
+var (
+	signaturesMu sync.RWMutex
+	// signatures are the registered signatures, checked before the built-in
+	// ones.
+	signatures []Signature
+)
+
+// RegisterSignature adds a signature to the ones DetectType checks. Registered
+// signatures are checked before the built-in ones, the last registered first,
+// so they can override them.
+func RegisterSignature(sig Signature) {
+	signaturesMu.Lock()
+	defer signaturesMu.Unlock()
+	signatures = append([]Signature{sig}, signatures...)
+}
+
+// builtinSignatures are the signatures of common binary formats. More
+// specific signatures come before less specific ones with the same magic.
+var builtinSignatures = []Signature{
+	// images
+	{Magic: []byte("\x89PNG\r\n\x1a\n"), MIME: "image/png", Category: CategoryImage},
+	{Magic: []byte("\xff\xd8\xff"), MIME: "image/jpeg", Category: CategoryImage},
+	{Magic: []byte("GIF87a"), MIME: "image/gif", Category: CategoryImage},
+	{Magic: []byte("GIF89a"), MIME: "image/gif", Category: CategoryImage},
+	{Magic: []byte("RIFF"), Match: riffType("WEBP"), MIME: "image/webp", Category: CategoryImage},
+	{Magic: []byte("BM"), Match: isBMP, MIME: "image/bmp", Category: CategoryImage},
+	{Magic: []byte("II*\x00"), MIME: "image/tiff", Category: CategoryImage},
+	{Magic: []byte("MM\x00*"), MIME: "image/tiff", Category: CategoryImage},
+	{Magic: []byte("\x00\x00\x01\x00"), MIME: "image/vnd.microsoft.icon", Category: CategoryImage},
+	// audio and video
+	{Magic: []byte("ID3"), Match: isID3, MIME: "audio/mpeg", Category: CategoryAudio},
+	{Magic: []byte("fLaC"), MIME: "audio/flac", Category: CategoryAudio},
+	{Magic: []byte("OggS"), MIME: "audio/ogg", Category: CategoryAudio},
+	{Magic: []byte("RIFF"), Match: riffType("WAVE"), MIME: "audio/wav", Category: CategoryAudio},
+	{Magic: []byte("RIFF"), Match: riffType("AVI "), MIME: "video/x-msvideo", Category: CategoryVideo},
+	// ISO base media files tell their format by the major brand after "ftyp"
+	{Offset: 4, Magic: []byte("ftypheic"), MIME: "image/heic", Category: CategoryImage},
+	{Offset: 4, Magic: []byte("ftypheix"), MIME: "image/heic", Category: CategoryImage},
+	{Offset: 4, Magic: []byte("ftypmif1"), MIME: "image/heif", Category: CategoryImage},
+	{Offset: 4, Magic: []byte("ftypavif"), MIME: "image/avif", Category: CategoryImage},
+	{Offset: 4, Magic: []byte("ftypavis"), MIME: "image/avif", Category: CategoryImage},
+	{Offset: 4, Magic: []byte("ftypM4A "), MIME: "audio/mp4", Category: CategoryAudio},
+	{Offset: 4, Magic: []byte("ftypM4B "), MIME: "audio/mp4", Category: CategoryAudio},
+	{Offset: 4, Magic: []byte("ftyp3gp"), MIME: "video/3gpp", Category: CategoryVideo},
+	{Offset: 4, Magic: []byte("ftypqt"), MIME: "video/quicktime", Category: CategoryVideo},
+	{Offset: 4, Magic: []byte("ftyp"), MIME: "video/mp4", Category: CategoryVideo},
+	{Magic: []byte("\x1a\x45\xdf\xa3"), MIME: "video/x-matroska", Category: CategoryVideo},
+	// documents
+	{Magic: []byte("%PDF-"), MIME: "application/pdf", Category: CategoryDocument},
+	// archives
+	{Magic: []byte("PK\x03\x04"), MIME: "application/zip", Category: CategoryArchive},
+	{Magic: []byte("PK\x05\x06"), MIME: "application/zip", Category: CategoryArchive},
+	{Magic: []byte("\x1f\x8b"), MIME: "application/gzip", Category: CategoryArchive},
+	{Magic: []byte("BZh"), Match: isBzip2, MIME: "application/x-bzip2", Category: CategoryArchive},
+	{Magic: []byte("\xfd7zXZ\x00"), MIME: "application/x-xz", Category: CategoryArchive},
+	{Magic: []byte("\x28\xb5\x2f\xfd"), MIME: "application/zstd", Category: CategoryArchive},
+	{Magic: []byte("7z\xbc\xaf\x27\x1c"), MIME: "application/x-7z-compressed", Category: CategoryArchive},
+	{Magic: []byte("Rar!\x1a\x07"), MIME: "application/vnd.rar", Category: CategoryArchive},
+	{Offset: 257, Magic: []byte("ustar"), MIME: "application/x-tar", Category: CategoryArchive},
+	// executables
+	{Magic: []byte("\x7fELF"), MIME: "application/x-executable", Category: CategoryExecutable},
+	{Magic: []byte("\xfe\xed\xfa\xce"), MIME: "application/x-mach-binary", Category: CategoryExecutable},
+	{Magic: []byte("\xfe\xed\xfa\xcf"), MIME: "application/x-mach-binary", Category: CategoryExecutable},
+	{Magic: []byte("\xce\xfa\xed\xfe"), MIME: "application/x-mach-binary", Category: CategoryExecutable},
+	{Magic: []byte("\xcf\xfa\xed\xfe"), MIME: "application/x-mach-binary", Category: CategoryExecutable},
+	{Magic: []byte("\xca\xfe\xba\xbe"), Match: isUniversalBinary, MIME: "application/x-mach-binary", Category: CategoryExecutable},
+	{Magic: []byte("\xca\xfe\xba\xbe"), MIME: "application/java-vm", Category: CategoryExecutable},
+	{Magic: []byte("MZ"), Match: isPE, MIME: peMIME, Category: CategoryExecutable},
+}
+
+// peMIME is the media type of Windows executables and libraries.
+const peMIME = "application/vnd.microsoft.portable-executable"
+
+// riffType matches a RIFF container with the form type.
+func riffType(form string) func([]byte) bool {
+	return func(header []byte) bool {
+		return len(header) >= 12 && string(header[8:12]) == form
+	}
+}
+
+// isBMP checks the reserved fields of the bitmap file header, since "BM"
+// alone often starts text.
+func isBMP(header []byte) bool {
+	return len(header) >= 14 && binary.LittleEndian.Uint32(header[6:10]) == 0
+}
+
+// isID3 checks the major version of an ID3v2 tag.
+func isID3(header []byte) bool {
+	return len(header) >= 10 && header[3] >= 2 && header[3] <= 4
+}
+
+// isBzip2 checks the block size of a bzip2 stream.
+func isBzip2(header []byte) bool {
+	return len(header) >= 4 && header[3] >= '1' && header[3] <= '9'
+}
+
+// isPE checks that the DOS header of an executable points to a PE header
+// within the header, since "MZ" alone often starts text.
+func isPE(header []byte) bool {
+	if len(header) < 64 {
+		return false
+	}
+	// e_lfanew is checked as unsigned, it may overflow an int on 32-bit platforms
+	offset := binary.LittleEndian.Uint32(header[60:64])
+	return offset <= uint32(len(header)-4) && string(header[offset:offset+4]) == "PE\x00\x00"
+}
+
+// isUniversalBinary tells universal Mach-O binaries from Java class files,
+// which share the magic. Universal binaries store a small number of
+// architectures where class files store their version, 45 or higher.
+func isUniversalBinary(header []byte) bool {
+	return len(header) >= 8 && binary.BigEndian.Uint32(header[4:8]) < 45
+}
+
+// DetectType detects the type of the file at path from its first bytes. See
+// DetectTypeReader.
+func DetectType(path string) (FileType, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return FileType{}, err
+	}
+	defer f.Close()
+	return DetectTypeReader(f)
+}
+
+// DetectTypeReader detects the type of the content from its first bytes. The
+// registered and built-in signatures are checked first. Content without a
+// signature is text if it's valid UTF-8 or UTF-16 with a byte order mark and
+// has no control characters; text starting with a shebang line is a script.
+// Everything else is CategoryUnknown with the MIME type
+// "application/octet-stream".
+func DetectTypeReader(r io.Reader) (FileType, error) {
+	header := make([]byte, sniffLen)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return FileType{}, err
+	}
+	return detectType(header[:n], n == sniffLen), nil
+}
+
+// detectType detects the type from the header. truncated is set if the
+// header isn't the whole content.
+func detectType(header []byte, truncated bool) FileType {
+	signaturesMu.RLock()
+	sigs := append(append([]Signature{}, signatures...), builtinSignatures...)
+	signaturesMu.RUnlock()
+
+	for _, sig := range sigs {
+		if sig.matches(header) {
+			return FileType{MIME: sig.MIME, Category: sig.Category}
+		}
+	}
+
+	encoding, text := detectEncoding(header, truncated)
+	if !text {
+		return FileType{MIME: "application/octet-stream", Category: CategoryUnknown}
+	}
+
+	t := FileType{MIME: "text/plain", Category: CategoryText, Encoding: encoding}
+	if interpreter, ok := shebang(header, encoding); ok {
+		t.Category = CategoryScript
+		t.Interpreter = interpreter
+		t.MIME = scriptMIME(interpreter)
+	}
+	return t
+}
+
+// detectEncoding reports the encoding of text, or false for binary content.
+func detectEncoding(header []byte, truncated bool) (string, bool) {
+	switch {
+	case bytes.HasPrefix(header, []byte("\xef\xbb\xbf")):
+		header = header[3:]
+	case bytes.HasPrefix(header, []byte("\xff\xfe")):
+		return "utf-16le", true
+	case bytes.HasPrefix(header, []byte("\xfe\xff")):
+		return "utf-16be", true
+	}
+
+	ascii := true
+	if truncated {
+		// the last character may be cut off
+		for i := 0; i < utf8.UTFMax && len(header) > 0; i++ {
+			if r, _ := utf8.DecodeLastRune(header); r != utf8.RuneError {
+				break
+			}
+			header = header[:len(header)-1]
+			ascii = false
+		}
+	}
+	if !utf8.Valid(header) {
+		return "", false
+	}
+
+	for _, b := range header {
+		if isControl(b) {
+			return "", false
+		}
+		if b >= utf8.RuneSelf {
+			ascii = false
+		}
+	}
+	if ascii {
+		return "us-ascii", true
+	}
+	return "utf-8", true
+}
+
+// isControl reports whether the byte is a control character that doesn't
+// occur in text. Tabs, line breaks, form feeds and escapes are allowed.
+func isControl(b byte) bool {
+	switch b {
+	case '\t', '\n', '\r', '\f', 0x1b:
+		return false
+	}
+	return b < 0x20 || b == 0x7f
+}
+
+// shebang returns the interpreter of a script, without directories and
+// `env`, e.g. "python3".
+func shebang(header []byte, encoding string) (string, bool) {
+	if encoding != "us-ascii" && encoding != "utf-8" {
+		return "", false
+	}
+	header = bytes.TrimPrefix(header, []byte("\xef\xbb\xbf"))
+	if !bytes.HasPrefix(header, []byte("#!")) {
+		return "", false
+	}
+
+	line := string(header[2:])
+	if i := strings.IndexAny(line, "\r\n"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return "", true
+	}
+
+	interpreter := path.Base(fields[0])
+	if interpreter == "env" {
+		interpreter = ""
+		for _, arg := range fields[1:] {
+			// skip the options and variables of env, e.g. `-S` or `A=B`
+			if !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") {
+				interpreter = path.Base(arg)
+				break
+			}
+		}
+	}
+	return interpreter, true
+}
+
+// scriptMIME returns the media type of a script run by the interpreter.
+func scriptMIME(interpreter string) string {
+	name := strings.TrimRight(interpreter, "0123456789.")
+	switch name {
+	case "sh", "bash", "dash", "ksh", "zsh", "ash":
+		return "text/x-shellscript"
+	case "python":
+		return "text/x-python"
+	case "perl":
+		return "text/x-perl"
+	case "ruby":
+		return "text/x-ruby"
+	case "node", "deno", "bun":
+		return "text/javascript"
+	case "php":
+		return "application/x-httpd-php"
+	default:
+		return "text/x-script"
+	}
+}
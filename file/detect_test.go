@@ -0,0 +1,141 @@
+package file
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// peHeader returns the start of a Windows executable.
+func peHeader() []byte {
+	header := make([]byte, 256)
+	copy(header, "MZ")
+	binary.LittleEndian.PutUint32(header[60:], 128)
+	copy(header[128:], "PE\x00\x00")
+	return header
+}
+
+func TestDetectTypeReader(t *testing.T) {
+	tar := make([]byte, 512)
+	copy(tar[257:], "ustar\x0000")
+	fat := []byte("\xca\xfe\xba\xbe\x00\x00\x00\x02")
+	class := []byte("\xca\xfe\xba\xbe\x00\x00\x00\x37")
+	malformedPE := peHeader()
+	binary.LittleEndian.PutUint32(malformedPE[60:], 0xfffffffc)
+
+	tests := []struct {
+		name     string
+		content  []byte
+		mime     string
+		category Category
+	}{
+		{name: "png", content: []byte("\x89PNG\r\n\x1a\n\x00\x00"), mime: "image/png", category: CategoryImage},
+		{name: "jpeg", content: []byte("\xff\xd8\xff\xe0"), mime: "image/jpeg", category: CategoryImage},
+		{name: "webp", content: []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), mime: "image/webp", category: CategoryImage},
+		{name: "wav", content: []byte("RIFF\x00\x00\x00\x00WAVEfmt "), mime: "audio/wav", category: CategoryAudio},
+		{name: "mp4", content: []byte("\x00\x00\x00\x18ftypmp42"), mime: "video/mp4", category: CategoryVideo},
+		{name: "quicktime", content: []byte("\x00\x00\x00\x14ftypqt  "), mime: "video/quicktime", category: CategoryVideo},
+		{name: "heic", content: []byte("\x00\x00\x00\x18ftypheic"), mime: "image/heic", category: CategoryImage},
+		{name: "avif", content: []byte("\x00\x00\x00\x1cftypavif"), mime: "image/avif", category: CategoryImage},
+		{name: "m4a", content: []byte("\x00\x00\x00\x20ftypM4A "), mime: "audio/mp4", category: CategoryAudio},
+		{name: "pdf", content: []byte("%PDF-1.7\n"), mime: "application/pdf", category: CategoryDocument},
+		{name: "zip", content: []byte("PK\x03\x04\x14\x00"), mime: "application/zip", category: CategoryArchive},
+		{name: "gzip", content: []byte("\x1f\x8b\x08\x00"), mime: "application/gzip", category: CategoryArchive},
+		{name: "tar", content: tar, mime: "application/x-tar", category: CategoryArchive},
+		{name: "elf", content: []byte("\x7fELF\x02\x01\x01\x00"), mime: "application/x-executable", category: CategoryExecutable},
+		{name: "mach-o", content: []byte("\xcf\xfa\xed\xfe\x07\x00\x00\x01"), mime: "application/x-mach-binary", category: CategoryExecutable},
+		{name: "universal mach-o", content: fat, mime: "application/x-mach-binary", category: CategoryExecutable},
+		{name: "java class", content: class, mime: "application/java-vm", category: CategoryExecutable},
+		{name: "pe", content: peHeader(), mime: "application/vnd.microsoft.portable-executable", category: CategoryExecutable},
+		{name: "pe header out of range", content: malformedPE, mime: "application/octet-stream", category: CategoryUnknown},
+		{name: "text starting with MZ", content: []byte(strings.Repeat("MZ is not an executable. ", 4)), mime: "text/plain", category: CategoryText},
+		{name: "text starting with BZh", content: []byte("BZh, said the bee"), mime: "text/plain", category: CategoryText},
+		{name: "binary", content: []byte("\x00\x01\x02\x03"), mime: "application/octet-stream", category: CategoryUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectTypeReader(bytes.NewReader(tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, tt.mime, got.MIME)
+			assert.Equal(t, tt.category, got.Category)
+		})
+	}
+}
+
+func TestDetectTypeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		mime        string
+		category    Category
+		encoding    string
+		interpreter string
+	}{
+		{name: "ascii", content: "hello\tworld\r\n", mime: "text/plain", category: CategoryText, encoding: "us-ascii"},
+		{name: "utf-8", content: "こんにちは\n", mime: "text/plain", category: CategoryText, encoding: "utf-8"},
+		{name: "utf-8 bom", content: "\xef\xbb\xbfhello", mime: "text/plain", category: CategoryText, encoding: "us-ascii"},
+		{name: "utf-16le", content: "\xff\xfeh\x00i\x00", mime: "text/plain", category: CategoryText, encoding: "utf-16le"},
+		{name: "invalid utf-8", content: "hello \xff", mime: "application/octet-stream", category: CategoryUnknown},
+		{name: "shell", content: "#!/bin/sh\necho hi\n", mime: "text/x-shellscript", category: CategoryScript, encoding: "us-ascii", interpreter: "sh"},
+		{name: "env", content: "#!/usr/bin/env -S python3 -u\n", mime: "text/x-python", category: CategoryScript, encoding: "us-ascii", interpreter: "python3"},
+		{name: "unknown interpreter", content: "#! /opt/bin/awk -f\r\n", mime: "text/x-script", category: CategoryScript, encoding: "us-ascii", interpreter: "awk"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DetectTypeReader(strings.NewReader(tt.content))
+			require.NoError(t, err)
+			assert.Equal(t, FileType{MIME: tt.mime, Category: tt.category, Encoding: tt.encoding, Interpreter: tt.interpreter}, got)
+			assert.Equal(t, tt.category == CategoryScript, got.IsExecutable())
+		})
+	}
+
+	// a character cut off at the end of the sniffed bytes is still text
+	content := strings.Repeat("a", sniffLen-1) + "é"
+	got, err := DetectTypeReader(strings.NewReader(content))
+	require.NoError(t, err)
+	assert.Equal(t, "utf-8", got.Encoding)
+}
+
+func TestDetectType(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "program")
+	require.NoError(t, os.WriteFile(path, peHeader(), 0644))
+
+	got, err := DetectType(path)
+	require.NoError(t, err)
+	assert.True(t, got.IsExecutable())
+	assert.Equal(t, "executable", got.Category.String())
+
+	_, err = DetectType(filepath.Join(dir, "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestRegisterSignature(t *testing.T) {
+	saved := signatures
+	t.Cleanup(func() { signatures = saved })
+
+	RegisterSignature(Signature{Magic: []byte("GORKY"), MIME: "application/x-gorky", Category: CategoryDocument})
+	RegisterSignature(Signature{
+		Match:    func(header []byte) bool { return bytes.HasPrefix(header, []byte("%PDF-0")) },
+		MIME:     "application/x-old-pdf",
+		Category: CategoryDocument,
+	})
+
+	got, err := DetectTypeReader(strings.NewReader("GORKY\x00\x01"))
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-gorky", got.MIME)
+
+	// registered signatures override the built-in ones
+	got, err = DetectTypeReader(strings.NewReader("%PDF-0.1"))
+	require.NoError(t, err)
+	assert.Equal(t, "application/x-old-pdf", got.MIME)
+	got, err = DetectTypeReader(strings.NewReader("%PDF-1.7"))
+	require.NoError(t, err)
+	assert.Equal(t, "application/pdf", got.MIME)
+}
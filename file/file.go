@@ -1,13 +1,12 @@
 // Package file defines functions to determine the file contents.
-// For example, IsFile() returns whether the path is a file or not with a bool value.
+// For example, IsFile() returns whether the path is a file or not with a bool value,
+// and DetectType() returns the type of the file detected from its content.
 package file
 
 import (
-	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
-	"runtime"
 	"strings"
 )
 
@@ -65,25 +64,24 @@ func IsZero(path string) bool {
 	return (err == nil) && (stat.Size() == 0)
 }
 
-// IsReadable reports whether the path exists and is readable.
+// IsReadable reports whether the path exists and is readable by the current
+// process. See CheckAccess for the reason if it's not.
 func IsReadable(path string) bool {
-	stat, err := os.Stat(path)
-	return (err == nil) && ((stat.Mode() & Readable) != 0)
+	return CheckAccess(path, ReadAccess) == nil
 }
 
-// IsWritable reports whether the path exists and is writable.
+// IsWritable reports whether the path exists and is writable by the current
+// process. See CheckAccess for the reason if it's not.
 func IsWritable(path string) bool {
-	stat, err := os.Stat(path)
-	return (err == nil) && ((stat.Mode() & Writable) != 0)
+	return CheckAccess(path, WriteAccess) == nil
 }
 
-// IsExecutable reports whether the path exists and is executable.
+// IsExecutable reports whether the path exists and is executable by the
+// current process. On Windows, files are executable if they are PE
+// executables or their extension is listed in PATHEXT. See CheckAccess for the
+// reason if it's not.
 func IsExecutable(path string) bool {
-	if runtime.GOOS == "windows" {
-		return strings.HasSuffix(path, ".exe")
-	}
-	stat, err := os.Stat(path)
-	return (err == nil) && ((stat.Mode() & Executable) != 0)
+	return CheckAccess(path, ExecuteAccess) == nil
 }
 
 // IsHiddenFile reports whether the path exists and is included hidden file.
@@ -94,24 +92,3 @@ func IsHiddenFile(filePath string) bool {
 	}
 	return false
 }
-
-// Copy copy file to destination path
-func Copy(src string, dest string) error {
-	s, err := os.Open(src)
-	if err != nil {
-		return err
-	}
-	defer s.Close()
-
-	d, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-	defer d.Close()
-
-	_, err = io.Copy(d, s)
-	if err != nil {
-		return err
-	}
-	return d.Close()
-}
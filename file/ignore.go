@@ -0,0 +1,154 @@
+package file
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// ignorePattern is a compiled line of a gitignore file.
+type ignorePattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreMatcher matches slash separated relative paths against gitignore
+// patterns. Like in git, the last matching pattern decides.
+type ignoreMatcher []ignorePattern
+
+// newIgnoreMatcher compiles the lines of a gitignore file. Blank lines and
+// comments are skipped.
+func newIgnoreMatcher(lines []string) (ignoreMatcher, error) {
+	var m ignoreMatcher
+	for _, line := range lines {
+		p, ok, err := parseIgnorePattern(line)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			m = append(m, p)
+		}
+	}
+	return m, nil
+}
+
+// parseIgnorePattern compiles a line of a gitignore file. It reports false
+// for blank lines and comments.
+func parseIgnorePattern(line string) (ignorePattern, bool, error) {
+	var p ignorePattern
+	pattern := strings.TrimRight(line, " \t\r")
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return p, false, nil
+	}
+
+	if strings.HasPrefix(pattern, "!") {
+		p.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, `\#`) || strings.HasPrefix(pattern, `\!`) {
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		p.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return p, false, nil
+	}
+
+	// a pattern with a slash is relative to the root, others match at any depth
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegexp(pattern)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return p, false, fmt.Errorf("invalid ignore pattern %q: %w", line, err)
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// globToRegexp converts a gitignore glob to a regular expression. `*` and `?`
+// don't match a slash, `**` matches any number of directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			b.WriteString(classToRegexp(glob[i+1 : i+1+end]))
+			i += end + 1
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// classToRegexp converts the body of a bracket expression to a regular
+// expression class. Apart from ranges and the leading `!`, every character is
+// literal, and a negated class doesn't match a slash.
+func classToRegexp(class string) string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if strings.HasPrefix(class, "!") {
+		b.WriteString("^/")
+		class = class[1:]
+	}
+	escaped := false
+	for _, r := range class {
+		switch {
+		case r == '\\' && !escaped:
+			escaped = true
+			continue
+		case r == '-' && !escaped:
+			b.WriteRune(r)
+		case r == '-':
+			b.WriteString(`\-`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+		escaped = false
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// match reports whether the slash separated relative path is ignored.
+func (m ignoreMatcher) match(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range m {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
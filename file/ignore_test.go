@@ -0,0 +1,61 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m, err := newIgnoreMatcher([]string{
+		"# comment",
+		"",
+		"*.log",
+		"!keep.log",
+		"build/",
+		"/vendor",
+		"docs/**/*.tmp",
+		"a?c",
+		"[!x]y",
+		"[\\d]z",
+		"[.^]w",
+		"[a-c]v",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		rel     string
+		isDir   bool
+		ignored bool
+	}{
+		{rel: "debug.log", ignored: true},
+		{rel: "sub/dir/debug.log", ignored: true},
+		{rel: "keep.log"},
+		{rel: "sub/keep.log"},
+		{rel: "build", isDir: true, ignored: true},
+		{rel: "sub/build", isDir: true, ignored: true},
+		{rel: "build"},
+		{rel: "vendor", isDir: true, ignored: true},
+		{rel: "sub/vendor", isDir: true},
+		{rel: "docs/a.tmp", ignored: true},
+		{rel: "docs/x/y/a.tmp", ignored: true},
+		{rel: "other/a.tmp"},
+		{rel: "abc", ignored: true},
+		{rel: "a/c"},
+		{rel: "ay", ignored: true},
+		{rel: "xy"},
+		{rel: "/y"},
+		{rel: "a/y"},
+		{rel: "dz", ignored: true},
+		{rel: "1z"},
+		{rel: ".w", ignored: true},
+		{rel: "^w", ignored: true},
+		{rel: "aw"},
+		{rel: "bv", ignored: true},
+		{rel: "-v"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.ignored, m.match(tt.rel, tt.isDir), tt.rel)
+	}
+}
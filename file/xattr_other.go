@@ -0,0 +1,9 @@
+//go:build !darwin && !freebsd && !linux && !netbsd
+
+package file
+
+// copyXattrs does nothing, extended attributes are not supported on this
+// platform.
+func copyXattrs(string, string, bool) error {
+	return nil
+}
@@ -0,0 +1,27 @@
+//go:build darwin || freebsd || linux || netbsd
+
+package file
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestCopyXattrs(t *testing.T) {
+	dir := t.TempDir()
+	src, dest := filepath.Join(dir, "src"), filepath.Join(dir, "dest")
+	writeTestFile(t, src, "content", 0644)
+	if err := unix.Setxattr(src, "user.golden", []byte("value"), 0); err != nil {
+		t.Skipf("the file system does not support extended attributes: %s", err)
+	}
+
+	require.NoError(t, Copy(src, dest, WithPreserveXattrs(true)))
+	buf := make([]byte, 16)
+	n, err := unix.Getxattr(dest, "user.golden", buf)
+	require.NoError(t, err)
+	assert.Equal(t, "value", string(buf[:n]))
+}
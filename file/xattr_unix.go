@@ -0,0 +1,72 @@
+//go:build darwin || freebsd || linux || netbsd
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// copyXattrs copies the extended attributes of the source to the destination.
+// Attributes outside the `user.` namespace that may not be set, like
+// `security.selinux` for unprivileged users on Linux, are skipped.
+func copyXattrs(src string, dest string, link bool) error {
+	list, get, set := unix.Listxattr, unix.Getxattr, unix.Setxattr
+	if link {
+		list, get, set = unix.Llistxattr, unix.Lgetxattr, unix.Lsetxattr
+	}
+
+	names, err := readXattr(func(buf []byte) (int, error) { return list(src, buf) })
+	if errors.Is(err, unix.ENOTSUP) {
+		return nil
+	}
+	if err != nil {
+		return &os.PathError{Op: "listxattr", Path: src, Err: err}
+	}
+
+	for _, name := range bytes.Split(names, []byte{0}) {
+		if len(name) == 0 {
+			continue
+		}
+		attr := string(name)
+
+		value, err := readXattr(func(buf []byte) (int, error) { return get(src, attr, buf) })
+		if err != nil {
+			return &os.PathError{Op: "getxattr", Path: src, Err: err}
+		}
+
+		err = set(dest, attr, value, 0)
+		if err != nil && !strings.HasPrefix(attr, "user.") && (errors.Is(err, unix.EPERM) || errors.Is(err, unix.ENOTSUP)) {
+			continue
+		}
+		if err != nil {
+			return &os.PathError{Op: "setxattr", Path: dest, Err: err}
+		}
+	}
+	return nil
+}
+
+// readXattr reads a value of unknown size, retrying if it grows between the
+// size query and the read.
+func readXattr(read func(buf []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := read(nil)
+		if err != nil || size == 0 {
+			return nil, err
+		}
+
+		buf := make([]byte, size)
+		n, err := read(buf)
+		if errors.Is(err, unix.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
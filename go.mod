@@ -3,9 +3,13 @@ module github.com/nao1215/gorky
 go 1.19
 
 require (
+	github.com/klauspost/compress v1.16.5
 	github.com/pmezard/go-difflib v1.0.0
 	github.com/sergi/go-diff v1.3.1
 	github.com/stretchr/testify v1.8.2
+	golang.org/x/net v0.10.0
+	golang.org/x/sys v0.8.0
+	google.golang.org/protobuf v1.30.0
 )
 
 require (
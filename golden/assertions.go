@@ -15,20 +15,35 @@ import (
 // golden files. If the update flag is set, it will also update the golden
 // file.
 //
+// The options override the options of the tester for this call only, like
+// they do for all other assertions.
+//
 // `name` refers to the name of the test and it should typically be unique
 // within the package. Also it should be a valid file name (so keeping to
 // `a-z0-9\-\_` is a good idea).
-func (g *Golden) Assert(t *testing.T, name string, actualData []byte) {
+func (g *Golden) Assert(t *testing.T, name string, actualData []byte, opts ...Option) {
 	t.Helper()
+	g = g.with(t, opts)
+	report(t, g.check(t, name, actualData))
+}
+
+// check updates the golden file if requested and compares it with the actual
+// data. Every problem is returned, so the assertions can report it their own
+// way.
+func (g *Golden) check(t *testing.T, name string, actualData []byte) error {
 	if *update {
-		err := g.Update(t, name, actualData)
-		if err != nil {
-			t.Error(err)
-			t.FailNow()
+		if err := g.Update(t, name, actualData); err != nil {
+			return err
 		}
 	}
 
-	err := g.compare(t, name, actualData)
+	return g.conclude(t, name, g.GoldenFileName, g.compare(t, name, actualData))
+}
+
+// report reports the error returned by a comparison to the test. A missing
+// fixture stops the test, any other error marks it as failed.
+func report(t *testing.T, err error) {
+	t.Helper()
 	if err != nil {
 		{
 			var e *errFixtureNotFound
@@ -58,8 +73,9 @@ func (g *Golden) Assert(t *testing.T, name string, actualData []byte) {
 // `name` refers to the name of the test and it should typically be unique
 // within the package. Also it should be a valid file name (so keeping to
 // `a-z0-9\-\_` is a good idea).
-func (g *Golden) AssertJSON(t *testing.T, name string, actualJSONData interface{}) {
+func (g *Golden) AssertJSON(t *testing.T, name string, actualJSONData interface{}, opts ...Option) {
 	t.Helper()
+	g = g.with(t, opts)
 	js, err := json.MarshalIndent(actualJSONData, "", "  ")
 
 	if err != nil {
@@ -67,18 +83,22 @@ func (g *Golden) AssertJSON(t *testing.T, name string, actualJSONData interface{
 		t.FailNow()
 	}
 
-	g.Assert(t, name, normalizeLF(js))
+	g.withContentType("application/json").Assert(t, name, js)
 }
 
 // AssertXML compares the actual xml data received with expected data in the
 // golden files. If the update flag is set, it will also update the golden
 // file.
 //
+// With WithCanonicalXML, both documents are canonicalized before the
+// comparison.
+//
 // `name` refers to the name of the test and it should typically be unique
 // within the package. Also it should be a valid file name (so keeping to
 // `a-z0-9\-\_` is a good idea).
-func (g *Golden) AssertXML(t *testing.T, name string, actualXMLData interface{}) {
+func (g *Golden) AssertXML(t *testing.T, name string, actualXMLData interface{}, opts ...Option) {
 	t.Helper()
+	g = g.with(t, opts)
 	x, err := xml.MarshalIndent(actualXMLData, "", "  ")
 
 	if err != nil {
@@ -86,7 +106,12 @@ func (g *Golden) AssertXML(t *testing.T, name string, actualXMLData interface{})
 		t.FailNow()
 	}
 
-	g.Assert(t, name, normalizeLF(x))
+	if g.canonicalXML {
+		report(t, g.checkMarkup(t, name, x, xmlMarkup))
+		return
+	}
+
+	g.withContentType("application/xml").Assert(t, name, x)
 }
 
 // normalizeLF normalizes line feed character set across os (es)
@@ -109,43 +134,27 @@ func normalizeLF(d []byte) []byte {
 // the name of the test and it should typically be unique within the package.
 // Also it should be a valid file name (so keeping to `a-z0-9\-\_` is a good
 // idea).
-func (g *Golden) AssertWithTemplate(t *testing.T, name string, data interface{}, actualData []byte) {
+func (g *Golden) AssertWithTemplate(t *testing.T, name string, data interface{}, actualData []byte, opts ...Option) {
 	t.Helper()
+	g = g.with(t, opts)
+	report(t, g.checkTemplate(t, name, data, actualData))
+}
+
+// checkTemplate is the counterpart of check for template golden files.
+func (g *Golden) checkTemplate(t *testing.T, name string, data interface{}, actualData []byte) error {
 	if *update {
-		err := g.Update(t, name, actualData)
-		if err != nil {
-			t.Error(err)
-			t.FailNow()
+		if err := g.Update(t, name, actualData); err != nil {
+			return err
 		}
 	}
 
-	err := g.compareTemplate(t, name, data, actualData)
-	if err != nil {
-		{
-			var e *errFixtureNotFound
-			if errors.As(err, &e) {
-				t.Error(err)
-				t.FailNow()
-				return
-			}
-		}
-
-		{
-			var e *errFixtureMismatch
-			if errors.As(err, &e) {
-				t.Error(err)
-				return
-			}
-		}
-
-		t.Error(err)
-	}
+	return g.conclude(t, name, g.GoldenFileName, g.compareTemplate(t, name, data, actualData))
 }
 
 // compare is reading the golden fixture file and compare the stored data with
 // the actual data.
 func (g *Golden) compare(t *testing.T, name string, actualData []byte) error {
-	expectedData, err := os.ReadFile(g.GoldenFileName(t, name))
+	expectedData, err := readFixture(g.resolveFixture(t, name, g.GoldenFileName))
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -155,18 +164,16 @@ func (g *Golden) compare(t *testing.T, name string, actualData []byte) error {
 		return fmt.Errorf("expected %s to be nil", err.Error())
 	}
 
-	if !bytes.Equal(normalizeLF(actualData), normalizeLF(expectedData)) {
-		msg := "Result did not match the golden fixture. Diff is below:\n\n"
-		actual := string(actualData)
-		expected := string(expectedData)
+	// the digest is taken from the raw bytes, binary data has no line endings
+	if g.contentHash {
+		return g.compareDigest(t, name, actualData, expectedData)
+	}
 
-		if g.diffFn != nil {
-			msg += g.diffFn(actual, expected)
-		} else {
-			msg += Diff(g.diffEngine, actual, expected)
-		}
+	actualData = g.applyLineRules(g.normalize(actualData))
+	expectedData = g.applyLineRules(g.normalize(expectedData))
 
-		return newErrFixtureMismatch(msg)
+	if !bytes.Equal(actualData, expectedData) {
+		return g.newMismatch(string(actualData), string(expectedData))
 	}
 
 	return nil
@@ -175,7 +182,7 @@ func (g *Golden) compare(t *testing.T, name string, actualData []byte) error {
 // compareTemplate is reading the golden fixture file and compare the stored
 // data with the actual data.
 func (g *Golden) compareTemplate(t *testing.T, name string, data interface{}, actualData []byte) error {
-	expectedDataTmpl, err := os.ReadFile(g.GoldenFileName(t, name))
+	expectedDataTmpl, err := readFixture(g.resolveFixture(t, name, g.GoldenFileName))
 
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -190,7 +197,13 @@ func (g *Golden) compareTemplate(t *testing.T, name string, data interface{}, ac
 		missingKey = "default"
 	}
 
-	tmpl, err := template.New("test").Option("missingkey=" + missingKey).Parse(string(expectedDataTmpl))
+	matchers := &templateMatchers{leftDelim: g.templateLeftDelim, rightDelim: g.templateRightDelim}
+	tmpl, err := template.New("test").
+		Delims(g.templateLeftDelim, g.templateRightDelim).
+		Funcs(matchers.funcs()).
+		Funcs(g.templateFuncs).
+		Option("missingkey=" + missingKey).
+		Parse(string(g.normalize(expectedDataTmpl)))
 	if err != nil {
 		return fmt.Errorf("expected %s to be nil", err.Error())
 	}
@@ -201,19 +214,43 @@ func (g *Golden) compareTemplate(t *testing.T, name string, data interface{}, ac
 		return newErrMissingKey(fmt.Sprintf("Template error: %s", err.Error()))
 	}
 
-	if !bytes.Equal(actualData, expectedData.Bytes()) {
-		msg := "Result did not match the golden fixture. Diff is below:\n\n"
-		actual := string(actualData)
-		expected := expectedData.String()
-
-		if g.diffFn != nil {
-			msg += g.diffFn(actual, expected)
-		} else {
-			msg += Diff(g.diffEngine, actual, expected)
+	actualData = g.normalize(actualData)
+	expected := g.normalize(expectedData.Bytes())
+	if g.hasLineRules() && len(matchers.matchers) > 0 {
+		// the placeholders must be resolved before the lines are reordered
+		expected, err = matchers.resolveLines(g.filterLines(actualData), g.filterLines(expected), g.unorderedLines)
+		if err != nil {
+			return fmt.Errorf("could not match the template matchers: %w", err)
 		}
+	}
+	actualData = g.applyLineRules(actualData)
+	expected = g.applyLineRules(expected)
 
-		return newErrFixtureMismatch(msg)
+	ok, err := matchers.match(actualData, expected)
+	if err != nil {
+		return fmt.Errorf("could not match the template matchers: %w", err)
+	}
+	if !ok {
+		return g.newMismatch(string(actualData), matchers.display(expected))
 	}
 
 	return nil
 }
+
+// newMismatch returns an errFixtureMismatch whose message contains the diff
+// between the actual and the expected data.
+func (g *Golden) newMismatch(actual string, expected string) error {
+	msg := "Result did not match the golden fixture. Diff is below:\n\n"
+	msg += g.diff(actual, expected)
+
+	return newErrFixtureMismatch(msg)
+}
+
+// diff returns the diff between the actual and the expected data, produced by
+// the DiffFn if one is set and by the diff engine otherwise.
+func (g *Golden) diff(actual string, expected string) string {
+	if g.diffFn != nil {
+		return g.diffFn(actual, expected)
+	}
+	return Diff(g.diffEngine, actual, expected)
+}
@@ -0,0 +1,283 @@
+package golden
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// DBDumpFormat is used to enumerate the formats AssertDB can dump the
+// database state in.
+type DBDumpFormat int
+
+// noinspection GoUnusedConst
+const (
+	// DBDumpText dumps every table or query as an aligned plain text table.
+	DBDumpText DBDumpFormat = iota
+
+	// DBDumpCSV dumps every table or query as CSV.
+	DBDumpCSV
+)
+
+const (
+	// maskedValue replaces the values of the masked columns.
+	maskedValue = "<masked>"
+
+	// nullValue represents SQL NULL values.
+	nullValue = "NULL"
+)
+
+// tableNamePattern matches the entries of AssertDB that are table names
+// rather than queries.
+var tableNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// dbSection is the result of a single table or query.
+type dbSection struct {
+	title   string
+	columns []string
+	rows    [][]string
+	values  [][]interface{}
+}
+
+// AssertDB dumps the state of the database and compares it with the dump in
+// the golden files. If the update flag is set, it will also update the golden
+// file.
+//
+// Every entry of `queries` is either a table name, which dumps all rows of
+// the table sorted column by column (numbers by value, other values by their
+// rendered text), or a query whose rows are dumped in the
+// order they are returned. The values of columns registered with
+// WithMaskedColumns are replaced by `<masked>`, so volatile values like
+// timestamps or generated ids do not break the comparison. The dump format is
+// selected with WithDBDumpFormat.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertDB(t *testing.T, name string, db *sql.DB, queries []string, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	dump, err := g.dumpDB(db, queries)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if g.dbDumpFormat == DBDumpCSV {
+		contentType = "text/csv; charset=utf-8"
+	}
+
+	g.withContentType(contentType).Assert(t, name, dump)
+}
+
+// dumpDB runs the queries and renders their results.
+func (g *Golden) dumpDB(db *sql.DB, queries []string) ([]byte, error) {
+	sections := make([]dbSection, 0, len(queries))
+	for _, query := range queries {
+		section, err := g.querySection(db, query)
+		if err != nil {
+			return nil, err
+		}
+		sections = append(sections, section)
+	}
+
+	var buf bytes.Buffer
+	for i, section := range sections {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+
+		var err error
+		if g.dbDumpFormat == DBDumpCSV {
+			err = writeCSVSection(&buf, section)
+		} else {
+			writeTextSection(&buf, section)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// querySection runs a single table dump or query.
+func (g *Golden) querySection(db *sql.DB, query string) (dbSection, error) {
+	section := dbSection{title: query}
+	isTable := tableNamePattern.MatchString(query)
+	if isTable {
+		query = "SELECT * FROM " + query
+	}
+
+	rows, err := db.Query(query)
+	if err != nil {
+		return section, fmt.Errorf("could not dump %q: %w", section.title, err)
+	}
+	defer rows.Close()
+
+	section.columns, err = rows.Columns()
+	if err != nil {
+		return section, err
+	}
+
+	masked := make([]bool, len(section.columns))
+	for i, column := range section.columns {
+		masked[i] = g.isMaskedColumn(column)
+	}
+
+	for rows.Next() {
+		values := make([]interface{}, len(section.columns))
+		pointers := make([]interface{}, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return section, err
+		}
+
+		row := make([]string, len(values))
+		for i, value := range values {
+			if masked[i] {
+				row[i], values[i] = maskedValue, nil
+			} else {
+				row[i] = formatDBValue(value)
+			}
+		}
+		section.rows = append(section.rows, row)
+		section.values = append(section.values, values)
+	}
+	if err := rows.Err(); err != nil {
+		return section, err
+	}
+
+	if isTable {
+		section.sortRows()
+	}
+
+	return section, nil
+}
+
+// isMaskedColumn reports whether the values of the column must be masked.
+func (g *Golden) isMaskedColumn(column string) bool {
+	for _, masked := range g.maskedColumns {
+		if strings.EqualFold(masked, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortRows orders the rows column by column. Numbers are compared by value,
+// everything else by the rendered value, and masked values are equal.
+func (section *dbSection) sortRows() {
+	order := make([]int, len(section.rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		for column := range section.columns {
+			x, xOK := tableNumber(section.values[a][column])
+			y, yOK := tableNumber(section.values[b][column])
+			switch {
+			case xOK && yOK && x != y:
+				return x < y
+			case xOK && yOK:
+			case section.rows[a][column] != section.rows[b][column]:
+				return section.rows[a][column] < section.rows[b][column]
+			}
+		}
+		return false
+	})
+
+	rows := make([][]string, len(order))
+	values := make([][]interface{}, len(order))
+	for i, k := range order {
+		rows[i], values[i] = section.rows[k], section.values[k]
+	}
+	section.rows, section.values = rows, values
+}
+
+// formatDBValue renders a value returned by the database driver.
+func formatDBValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return nullValue
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return "0x" + hex.EncodeToString(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// writeTextSection renders the section as an aligned plain text table.
+func writeTextSection(buf *bytes.Buffer, section dbSection) {
+	fmt.Fprintf(buf, "-- %s\n", section.title)
+	writeAlignedRows(buf, section.columns, section.rows)
+	fmt.Fprintf(buf, "(%d rows)\n", len(section.rows))
+}
+
+// writeAlignedRows renders the columns and rows as an aligned plain text
+// table. The cells are escaped like in Markdown, so a value can't forge a row
+// or a column.
+func writeAlignedRows(buf *bytes.Buffer, columns []string, rows [][]string) {
+	columns = escapeCells(columns)
+	escaped := make([][]string, len(rows))
+	for i, row := range rows {
+		escaped[i] = escapeCells(row)
+	}
+	rows = escaped
+
+	widths := columnWidths(columns, rows, 0)
+
+	writeRow := func(cells []string) {
+		for i, cell := range cells {
+			if i > 0 {
+				buf.WriteString(" | ")
+			}
+			buf.WriteString(cell)
+			if i < len(cells)-1 {
+				buf.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			}
+		}
+		buf.WriteByte('\n')
+	}
+
+	writeRow(columns)
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeRow(separator)
+	for _, row := range rows {
+		writeRow(row)
+	}
+}
+
+// writeCSVSection renders the section as CSV, preceded by a comment line with
+// the table name or query.
+func writeCSVSection(buf *bytes.Buffer, section dbSection) error {
+	fmt.Fprintf(buf, "# %s\n", section.title)
+	w := csv.NewWriter(buf)
+	if err := w.Write(section.columns); err != nil {
+		return err
+	}
+	if err := w.WriteAll(section.rows); err != nil {
+		return err
+	}
+	return w.Error()
+}
@@ -0,0 +1,187 @@
+package golden
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memTable is a table of the in-process database used as a SQLite stand-in.
+type memTable struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// memDriver is a minimal database/sql driver that answers queries from a
+// fixed set of results.
+type memDriver struct {
+	results map[string]memTable
+}
+
+func (d *memDriver) Open(string) (driver.Conn, error) { return &memConn{driver: d}, nil }
+
+type memConn struct{ driver *memDriver }
+
+func (c *memConn) Prepare(query string) (driver.Stmt, error) {
+	result, ok := c.driver.results[query]
+	if !ok {
+		return nil, errors.New("no such table or query: " + query)
+	}
+	return &memStmt{result: result}, nil
+}
+func (c *memConn) Close() error              { return nil }
+func (c *memConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type memStmt struct{ result memTable }
+
+func (s *memStmt) Close() error  { return nil }
+func (s *memStmt) NumInput() int { return -1 }
+func (s *memStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s *memStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &memRows{table: s.result}, nil
+}
+
+type memRows struct {
+	table memTable
+	next  int
+}
+
+func (r *memRows) Columns() []string { return r.table.columns }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.next >= len(r.table.rows) {
+		return io.EOF
+	}
+	copy(dest, r.table.rows[r.next])
+	r.next++
+	return nil
+}
+
+func init() {
+	created := time.Date(2023, 6, 3, 12, 0, 0, 0, time.UTC)
+	sql.Register("golden-memdb", &memDriver{results: map[string]memTable{
+		"SELECT * FROM users": {
+			columns: []string{"id", "name", "avatar", "created_at"},
+			rows: [][]driver.Value{
+				{int64(2), "bob", []byte{0xff, 0x00}, created},
+				{int64(1), "alice", nil, created.Add(time.Hour)},
+			},
+		},
+		"SELECT * FROM items": {
+			columns: []string{"id", "note"},
+			rows: [][]driver.Value{
+				{int64(10), "ten"},
+				{int64(9), "nine\n10 | forged"},
+				{int64(9), "nine"},
+			},
+		},
+		"SELECT name FROM users ORDER BY id DESC": {
+			columns: []string{"name"},
+			rows:    [][]driver.Value{{"bob"}, {"alice, the first"}},
+		},
+	}})
+}
+
+func TestDumpDB(t *testing.T) {
+	db, err := sql.Open("golden-memdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	tests := map[string]struct {
+		options  []Option
+		queries  []string
+		expected string
+	}{
+		"text": {
+			queries: []string{"users", "SELECT name FROM users ORDER BY id DESC"},
+			expected: `-- users
+id | name  | avatar | created_at
+-- | ----- | ------ | --------------------
+1  | alice | NULL   | 2023-06-03T13:00:00Z
+2  | bob   | 0xff00 | 2023-06-03T12:00:00Z
+(2 rows)
+
+-- SELECT name FROM users ORDER BY id DESC
+name
+----------------
+bob
+alice, the first
+(2 rows)
+`,
+		},
+		"csv with masked columns": {
+			options: []Option{WithDBDumpFormat(DBDumpCSV), WithMaskedColumns("CREATED_AT", "avatar")},
+			queries: []string{"users", "SELECT name FROM users ORDER BY id DESC"},
+			expected: `# users
+id,name,avatar,created_at
+1,alice,<masked>,<masked>
+2,bob,<masked>,<masked>
+
+# SELECT name FROM users ORDER BY id DESC
+name
+bob
+"alice, the first"
+`,
+		},
+		"numeric order and escaped cells": {
+			queries: []string{"items"},
+			expected: `-- items
+id | note
+-- | --------------------
+9  | nine
+9  | nine<br>10 \| forged
+10 | ten
+(3 rows)
+`,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			dump, err := g.dumpDB(db, test.queries)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(dump))
+		})
+	}
+}
+
+func TestDumpDBUnknownTable(t *testing.T) {
+	db, err := sql.Open("golden-memdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	_, err = New(t).dumpDB(db, []string{"orders"})
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), `could not dump "orders"`))
+}
+
+func TestAssertDB(t *testing.T) {
+	db, err := sql.Open("golden-memdb", "")
+	require.NoError(t, err)
+	defer db.Close()
+
+	g := New(t, WithMaskedColumns("created_at"))
+	savedUpdateState := *update
+	*update = true
+	g.AssertDB(t, "users", db, []string{"users"})
+	*update = savedUpdateState
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	g.AssertDB(t, "users", db, []string{"users"})
+	data, err := os.ReadFile(g.GoldenFileName(t, "users"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "<masked>")
+}
@@ -0,0 +1,183 @@
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+const (
+	// digestPreviewSize is the number of leading bytes of the output that are
+	// stored as a preview in content hash fixtures.
+	digestPreviewSize = 64
+
+	// defaultArtifactsDirName is the folder name, relative to os.TempDir(),
+	// where the actual output is dumped when a content hash fixture does not
+	// match and no artifacts directory was configured.
+	defaultArtifactsDirName = "golden-artifacts"
+)
+
+// digest is the content of a golden fixture in content hash mode. Only the
+// SHA-256 sum, the length and a short preview of the output are stored.
+type digest struct {
+	sum     string
+	size    int
+	preview string
+}
+
+// newDigest calculates the digest of the data.
+func newDigest(data []byte) digest {
+	w := newDigestWriter()
+	_, _ = w.Write(data)
+	return w.digest()
+}
+
+// digestWriter calculates the digest of the data written to it, so outputs
+// can be hashed while they are streamed.
+type digestWriter struct {
+	hash    hash.Hash
+	size    int
+	preview []byte
+}
+
+// newDigestWriter returns an empty digestWriter.
+func newDigestWriter() *digestWriter {
+	return &digestWriter{hash: sha256.New()}
+}
+
+// Write adds the data to the digest. It never returns an error.
+func (w *digestWriter) Write(p []byte) (int, error) {
+	w.hash.Write(p)
+	w.size += len(p)
+	if n := digestPreviewSize - len(w.preview); n > 0 {
+		if n > len(p) {
+			n = len(p)
+		}
+		w.preview = append(w.preview, p[:n]...)
+	}
+	return len(p), nil
+}
+
+// digest returns the digest of the data written so far.
+func (w *digestWriter) digest() digest {
+	return digest{
+		sum:     hex.EncodeToString(w.hash.Sum(nil)),
+		size:    w.size,
+		preview: string(w.preview),
+	}
+}
+
+// parseDigest parses the content of a content hash fixture.
+func parseDigest(data []byte) (digest, error) {
+	var d digest
+	found := map[string]bool{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(normalizeLF(data)))
+	for scanner.Scan() {
+		field := strings.SplitN(scanner.Text(), ": ", 2)
+		if len(field) != 2 {
+			continue
+		}
+		key, value := field[0], field[1]
+
+		var err error
+		switch key {
+		case "sha256":
+			d.sum = value
+		case "size":
+			d.size, err = strconv.Atoi(value)
+		case "preview":
+			d.preview, err = strconv.Unquote(value)
+		default:
+			continue
+		}
+		if err != nil {
+			return digest{}, fmt.Errorf("invalid %s in content hash fixture: %w", key, err)
+		}
+		found[key] = true
+	}
+
+	if !found["sha256"] || !found["size"] {
+		return digest{}, fmt.Errorf("content hash fixture must contain the sha256 and size fields")
+	}
+
+	return d, nil
+}
+
+// bytes returns the content of the content hash fixture.
+func (d digest) bytes() []byte {
+	return []byte(fmt.Sprintf("sha256: %s\nsize: %d\npreview: %q\n", d.sum, d.size, d.preview))
+}
+
+// String returns a one line summary of the digest.
+func (d digest) String() string {
+	return fmt.Sprintf("sha256=%s size=%d preview=%q", d.sum, d.size, d.preview)
+}
+
+// compareDigest compares the digest stored in the golden fixture with the
+// digest of the actual data. On mismatch the actual data is dumped to the
+// artifacts directory for inspection.
+func (g *Golden) compareDigest(t *testing.T, name string, actualData []byte, fixture []byte) error {
+	expected, err := parseDigest(fixture)
+	if err != nil {
+		return err
+	}
+
+	actual := newDigest(actualData)
+	if actual.sum == expected.sum && actual.size == expected.size {
+		return nil
+	}
+
+	artifact, err := g.writeArtifact(t, name, actualData)
+	return newDigestMismatch(expected, actual, artifact, err)
+}
+
+// newDigestMismatch returns an errFixtureMismatch describing both digests and
+// where the actual output was saved to.
+func newDigestMismatch(expected digest, actual digest, artifact string, artifactErr error) error {
+	msg := fmt.Sprintf(
+		"Result did not match the golden digest.\n\nExpected: %s\nGot: %s\n",
+		expected, actual,
+	)
+
+	if artifactErr != nil {
+		msg += fmt.Sprintf("\nThe actual output could not be saved: %s\n", artifactErr)
+	} else {
+		msg += fmt.Sprintf("\nThe actual output was saved to %s\n", artifact)
+	}
+
+	return newErrFixtureMismatch(msg)
+}
+
+// ArtifactFileName returns the file name the actual output is dumped to when
+// a content hash fixture does not match.
+func (g *Golden) ArtifactFileName(t *testing.T, name string) string {
+	dir := g.artifactsDir
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), defaultArtifactsDirName)
+	}
+
+	return filepath.Join(dir, filepath.FromSlash(t.Name()), name)
+}
+
+// writeArtifact dumps the actual data to the artifacts directory.
+func (g *Golden) writeArtifact(t *testing.T, name string, actualData []byte) (string, error) {
+	artifact := g.ArtifactFileName(t, name)
+	if err := os.MkdirAll(filepath.Dir(artifact), g.dirPerms); err != nil {
+		return "", err
+	}
+
+	if err := os.WriteFile(artifact, actualData, g.filePerms); err != nil {
+		return "", err
+	}
+
+	return artifact, nil
+}
@@ -0,0 +1,98 @@
+package golden
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	data := bytes.Repeat([]byte{0x00, 0xff, 'a', '\n'}, 100)
+	d := newDigest(data)
+
+	assert.Equal(t, 400, d.size)
+	assert.Len(t, d.preview, digestPreviewSize)
+	assert.Len(t, d.sum, 64)
+
+	parsed, err := parseDigest(d.bytes())
+	require.NoError(t, err)
+	assert.Equal(t, d, parsed)
+}
+
+func TestParseDigestErrors(t *testing.T) {
+	tests := map[string][]byte{
+		"missing sum":     []byte("size: 3\n"),
+		"invalid size":    []byte("sha256: abc\nsize: three\n"),
+		"invalid preview": []byte("sha256: abc\nsize: 3\npreview: abc\n"),
+		"empty":           nil,
+	}
+
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseDigest(data)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestContentHashMode(t *testing.T) {
+	artifacts := t.TempDir()
+	g := New(t, WithContentHash(true), WithArtifactsDir(artifacts))
+	data := bytes.Repeat([]byte("frame"), 10000)
+
+	require.NoError(t, g.Update(t, "video", data))
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	stored, err := os.ReadFile(g.GoldenFileName(t, "video"))
+	require.NoError(t, err)
+	assert.Less(t, len(stored), 200)
+	assert.Contains(t, string(stored), "size: 50000")
+
+	assert.Nil(t, g.compare(t, "video", data))
+
+	changed := append([]byte("FRAME"), data[5:]...)
+	err = g.compare(t, "video", changed)
+	require.IsType(t, &errFixtureMismatch{}, err)
+
+	artifact := g.ArtifactFileName(t, "video")
+	assert.Equal(t, filepath.Join(artifacts, "TestContentHashMode", "video"), artifact)
+	assert.Contains(t, err.Error(), artifact)
+
+	dumped, err := os.ReadFile(artifact)
+	require.NoError(t, err)
+	assert.Equal(t, changed, dumped)
+}
+
+func TestContentHashModeRawBytes(t *testing.T) {
+	artifacts := t.TempDir()
+	g := New(t, WithFixtureDir(t.TempDir()), WithContentHash(true), WithArtifactsDir(artifacts))
+	data := []byte{1, '\r', '\n', 2}
+
+	require.NoError(t, g.Update(t, "binary", data))
+	stored, err := os.ReadFile(g.GoldenFileName(t, "binary"))
+	require.NoError(t, err)
+	assert.Equal(t, newDigest(data).bytes(), stored)
+	assert.Nil(t, g.compare(t, "binary", data))
+
+	// the line endings are part of the binary data
+	err = g.compare(t, "binary", []byte{1, '\n', 2})
+	require.IsType(t, &errFixtureMismatch{}, err)
+	dumped, err := os.ReadFile(g.ArtifactFileName(t, "binary"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, '\n', 2}, dumped)
+
+	// the same applies to streams
+	require.NoError(t, g.updateReader(t, "stream", bytes.NewReader(data)))
+	assert.NoError(t, g.compareReader(t, "stream", bytes.NewReader(data)))
+	err = g.compareReader(t, "stream", bytes.NewReader([]byte{1, '\n', 2}))
+	require.IsType(t, &errFixtureMismatch{}, err)
+	dumped, err = os.ReadFile(g.ArtifactFileName(t, "stream"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte{1, '\n', 2}, dumped)
+}
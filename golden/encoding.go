@@ -0,0 +1,122 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/binary"
+	"unicode/utf16"
+)
+
+// LineEnding is used to enumerate how line endings are handled before the
+// data is compared or written to the golden files.
+type LineEnding int
+
+// noinspection GoUnusedConst
+const (
+	// LF converts \r\n (windows) and \r (mac) line endings into \n (unix).
+	LF LineEnding = iota
+
+	// CRLF converts all line endings into \r\n (windows).
+	CRLF
+
+	// PreserveLineEndings keeps the line endings as they are, so differences
+	// in line endings are reported.
+	PreserveLineEndings
+)
+
+// TrailingNewline is used to enumerate how a line ending at the end of the
+// data is handled.
+type TrailingNewline int
+
+// noinspection GoUnusedConst
+const (
+	// PreserveTrailingNewline keeps the end of the data as it is.
+	PreserveTrailingNewline TrailingNewline = iota
+
+	// EnsureTrailingNewline appends a line ending to non-empty data that
+	// does not end with one.
+	EnsureTrailingNewline
+
+	// TrimTrailingNewline removes all line endings at the end of the data.
+	TrimTrailingNewline
+)
+
+var (
+	// utf8BOM is the byte order mark of UTF-8 encoded data.
+	utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+	// utf16LEBOM is the byte order mark of UTF-16 little endian data.
+	utf16LEBOM = []byte{0xff, 0xfe}
+
+	// utf16BEBOM is the byte order mark of UTF-16 big endian data.
+	utf16BEBOM = []byte{0xfe, 0xff}
+)
+
+// normalize applies the encoding and line ending policy to the data. It's
+// applied to the actual and the expected data of all assertions and to the
+// data written by Update, so all of them behave the same way.
+func (g *Golden) normalize(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+
+	if g.decodeUTF16 {
+		data = decodeUTF16(data)
+	}
+
+	if g.stripBOM {
+		data = bytes.TrimPrefix(data, utf8BOM)
+	}
+
+	switch g.lineEnding {
+	case LF:
+		data = normalizeLF(data)
+	case CRLF:
+		data = bytes.Replace(normalizeLF(data), []byte{10}, []byte{13, 10}, -1)
+	}
+
+	switch g.trailingNewline {
+	case EnsureTrailingNewline:
+		if len(data) > 0 && !bytes.HasSuffix(data, []byte{10}) && !bytes.HasSuffix(data, []byte{13}) {
+			data = append(data, g.newline()...)
+		}
+	case TrimTrailingNewline:
+		data = bytes.TrimRight(data, "\r\n")
+	}
+
+	return data
+}
+
+// newline returns the line ending appended by EnsureTrailingNewline.
+func (g *Golden) newline() []byte {
+	if g.lineEnding == CRLF {
+		return []byte{13, 10}
+	}
+	return []byte{10}
+}
+
+// decodeUTF16 converts UTF-16 encoded data into UTF-8. The byte order is taken
+// from the byte order mark. Without one, the data is only decoded if it looks
+// like UTF-16 encoded text, i.e. the first character has a zero high or low
+// byte. Any other data is returned as is.
+func decodeUTF16(data []byte) []byte {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, utf16LEBOM):
+		order, data = binary.LittleEndian, data[len(utf16LEBOM):]
+	case bytes.HasPrefix(data, utf16BEBOM):
+		order, data = binary.BigEndian, data[len(utf16BEBOM):]
+	case len(data)%2 == 0 && data[0] != 0 && data[1] == 0:
+		order = binary.LittleEndian
+	case len(data)%2 == 0 && data[0] == 0 && data[1] != 0:
+		order = binary.BigEndian
+	default:
+		return data
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+
+	return []byte(string(utf16.Decode(units)))
+}
@@ -0,0 +1,102 @@
+package golden
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		input    []byte
+		expected []byte
+	}{
+		"default converts to LF": {
+			input:    []byte("a\r\nb\rc\n"),
+			expected: []byte("a\nb\nc\n"),
+		},
+		"preserve line endings": {
+			options:  []Option{WithLineEndings(PreserveLineEndings)},
+			input:    []byte("a\r\nb\rc\n"),
+			expected: []byte("a\r\nb\rc\n"),
+		},
+		"CRLF": {
+			options:  []Option{WithLineEndings(CRLF)},
+			input:    []byte("a\r\nb\nc"),
+			expected: []byte("a\r\nb\r\nc"),
+		},
+		"ensure trailing newline": {
+			options:  []Option{WithTrailingNewline(EnsureTrailingNewline)},
+			input:    []byte("a\nb"),
+			expected: []byte("a\nb\n"),
+		},
+		"ensure trailing CRLF": {
+			options:  []Option{WithLineEndings(CRLF), WithTrailingNewline(EnsureTrailingNewline)},
+			input:    []byte("a"),
+			expected: []byte("a\r\n"),
+		},
+		"trim trailing newlines": {
+			options:  []Option{WithTrailingNewline(TrimTrailingNewline)},
+			input:    []byte("a\nb\r\n\n"),
+			expected: []byte("a\nb"),
+		},
+		"strip BOM": {
+			options:  []Option{WithStripBOM(true)},
+			input:    []byte("\xef\xbb\xbfhello"),
+			expected: []byte("hello"),
+		},
+		"keep BOM by default": {
+			input:    []byte("\xef\xbb\xbfhello"),
+			expected: []byte("\xef\xbb\xbfhello"),
+		},
+		"UTF-16 little endian with BOM": {
+			options:  []Option{WithUTF16Decoding(true)},
+			input:    []byte{0xff, 0xfe, 'h', 0, 'i', 0, '\r', 0, '\n', 0, 0xe9, 0},
+			expected: []byte("hi\né"),
+		},
+		"UTF-16 big endian without BOM": {
+			options:  []Option{WithUTF16Decoding(true)},
+			input:    []byte{0, 'h', 0, 'i'},
+			expected: []byte("hi"),
+		},
+		"UTF-8 input with UTF-16 decoding": {
+			options:  []Option{WithUTF16Decoding(true)},
+			input:    []byte("plain"),
+			expected: []byte("plain"),
+		},
+		"empty": {
+			options:  []Option{WithTrailingNewline(EnsureTrailingNewline)},
+			input:    []byte{},
+			expected: []byte{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			assert.Equal(t, test.expected, g.normalize(test.input))
+		})
+	}
+}
+
+func TestPolicyAppliesUniformly(t *testing.T) {
+	g := New(t, WithTrailingNewline(EnsureTrailingNewline))
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	require.NoError(t, g.Update(t, "policy", []byte("line 1\r\nline 2")))
+	data, err := os.ReadFile(g.GoldenFileName(t, "policy"))
+	require.NoError(t, err)
+	assert.Equal(t, "line 1\nline 2\n", string(data))
+
+	assert.Nil(t, g.compare(t, "policy", []byte("line 1\r\nline 2")))
+	assert.Nil(t, g.compareTemplate(t, "policy", nil, []byte("line 1\r\nline 2")))
+
+	preserve := New(t, WithLineEndings(PreserveLineEndings))
+	assert.IsType(t, &errFixtureMismatch{}, preserve.compare(t, "policy", []byte("line 1\r\nline 2\r\n")))
+	assert.IsType(t, &errFixtureMismatch{}, preserve.compareTemplate(t, "policy", nil, []byte("line 1\r\nline 2\r\n")))
+}
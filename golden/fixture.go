@@ -0,0 +1,246 @@
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Compression is used to enumerate the compression formats that are supported
+// for the golden fixtures.
+type Compression int
+
+// noinspection GoUnusedConst
+const (
+	// NoCompression stores the golden fixtures as they are. Fixtures whose
+	// name ends with a known compression suffix are still decompressed.
+	NoCompression Compression = iota
+
+	// Gzip stores the golden fixtures compressed with gzip and appends the
+	// `.gz` suffix to the fixture name.
+	Gzip
+
+	// Zstd stores the golden fixtures compressed with zstd and appends the
+	// `.zst` suffix to the fixture name.
+	Zstd
+)
+
+// suffix returns the file name suffix used for the compression format.
+func (c Compression) suffix() string {
+	switch c {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+	return ""
+}
+
+// compressionFor detects the compression format of a fixture by its suffix.
+func compressionFor(path string) Compression {
+	switch {
+	case strings.HasSuffix(path, Gzip.suffix()):
+		return Gzip
+	case strings.HasSuffix(path, Zstd.suffix()):
+		return Zstd
+	}
+	return NoCompression
+}
+
+// readFixture reads the golden fixture, transparently decompresses it and
+// strips the metadata header. The error of os.ReadFile is returned untouched,
+// so os.IsNotExist can be used to detect missing fixtures.
+func readFixture(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = decompress(compressionFor(path), data)
+	if err != nil {
+		return nil, err
+	}
+
+	_, data, err = splitMetadata(data)
+	return data, err
+}
+
+// writeFixture compresses the data according to the fixture suffix and writes
+// it to the golden fixture.
+func (g *Golden) writeFixture(path string, data []byte) error {
+	compressed, err := compress(compressionFor(path), data)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, compressed, g.filePerms)
+}
+
+// compress compresses the data. The output is deterministic, so updating an
+// unchanged fixture does not change the file.
+func compress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		var buf bytes.Buffer
+		w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+
+	case Zstd:
+		enc, err := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer enc.Close()
+		return enc.EncodeAll(data, nil), nil
+	}
+	return data, nil
+}
+
+// decompress reverts compress.
+func decompress(c Compression, data []byte) ([]byte, error) {
+	switch c {
+	case Gzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress gzip fixture: %w", err)
+		}
+		defer r.Close()
+		out, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress gzip fixture: %w", err)
+		}
+		return out, nil
+
+	case Zstd:
+		dec, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		defer dec.Close()
+		out, err := dec.DecodeAll(data, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not decompress zstd fixture: %w", err)
+		}
+		return out, nil
+	}
+	return data, nil
+}
+
+// openFixture opens the golden fixture for streaming, transparently
+// decompresses it and skips the metadata header. The error of os.Open is
+// returned untouched, so os.IsNotExist can be used to detect missing fixtures.
+func openFixture(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var r io.Reader = f
+	closeFn := f.Close
+	switch compressionFor(path) {
+	case Gzip:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not decompress gzip fixture: %w", err)
+		}
+		r = gz
+
+	case Zstd:
+		dec, err := zstd.NewReader(f, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		r = dec
+		closeFn = func() error {
+			dec.Close()
+			return f.Close()
+		}
+	}
+
+	br := bufio.NewReader(r)
+	if err := skipMetadata(br); err != nil {
+		closeFn()
+		return nil, err
+	}
+
+	return &fixtureReader{Reader: br, close: closeFn}, nil
+}
+
+// fixtureReader is the reader returned by openFixture.
+type fixtureReader struct {
+	io.Reader
+	close func() error
+}
+
+// Close closes the fixture file.
+func (r *fixtureReader) Close() error {
+	return r.close()
+}
+
+// writeFixtureFrom streams the header and the content of the src file to the
+// golden fixture, compressing it according to the fixture suffix.
+func (g *Golden) writeFixtureFrom(path string, header []byte, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, g.filePerms)
+	if err != nil {
+		return err
+	}
+
+	w, err := compressWriter(compressionFor(path), out)
+	if err == nil {
+		_, err = w.Write(header)
+	}
+	if err == nil {
+		_, err = io.Copy(w, in)
+	}
+	if err == nil {
+		err = w.Close()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// compressWriter returns a writer compressing the data written to it with the
+// same settings as compress.
+func compressWriter(c Compression, w io.Writer) (io.WriteCloser, error) {
+	switch c {
+	case Gzip:
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+	case Zstd:
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+	return nopWriteCloser{w}, nil
+}
+
+// nopWriteCloser adds a no-op Close method to a writer.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close does nothing.
+func (nopWriteCloser) Close() error {
+	return nil
+}
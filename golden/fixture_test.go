@@ -0,0 +1,93 @@
+package golden
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressionFor(t *testing.T) {
+	tests := map[string]struct {
+		path     string
+		expected Compression
+	}{
+		"plain":  {path: "testdata/a.golden", expected: NoCompression},
+		"gzip":   {path: "testdata/a.golden.gz", expected: Gzip},
+		"zstd":   {path: "testdata/a.golden.zst", expected: Zstd},
+		"in dir": {path: "testdata.gz/a.golden", expected: NoCompression},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, test.expected, compressionFor(test.path))
+		})
+	}
+}
+
+func TestCompressedFixtures(t *testing.T) {
+	data := bytes.Repeat([]byte("INSERT INTO users VALUES (1, 'gorky');\n"), 1000)
+
+	tests := map[string]struct {
+		options  []Option
+		fileName string
+	}{
+		"gzip option": {
+			options:  []Option{WithCompression(Gzip)},
+			fileName: "sql.golden.gz",
+		},
+		"zstd option": {
+			options:  []Option{WithCompression(Zstd)},
+			fileName: "sql.golden.zst",
+		},
+		"gzip suffix": {
+			options:  []Option{WithNameSuffix(".golden.gz")},
+			fileName: "sql.golden.gz",
+		},
+		"suffix and option are not duplicated": {
+			options:  []Option{WithNameSuffix(".golden.zst"), WithCompression(Zstd)},
+			fileName: "sql.golden.zst",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			goldenFile := g.GoldenFileName(t, "sql")
+			assert.Equal(t, filepath.Join(defaultFixtureDir, test.fileName), goldenFile)
+
+			require.NoError(t, g.Update(t, "sql", data))
+			defer func() {
+				assert.Nil(t, os.RemoveAll(g.fixtureDir))
+			}()
+
+			stored, err := os.ReadFile(goldenFile)
+			require.NoError(t, err)
+			assert.Less(t, len(stored), len(data))
+
+			assert.Nil(t, g.compare(t, "sql", data))
+			assert.IsType(t, &errFixtureMismatch{}, g.compare(t, "sql", data[1:]))
+
+			// updating with the same data must not change the fixture
+			require.NoError(t, g.Update(t, "sql", data))
+			again, err := os.ReadFile(goldenFile)
+			require.NoError(t, err)
+			assert.Equal(t, stored, again)
+		})
+	}
+}
+
+func TestReadFixtureCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"broken.golden.gz", "broken.golden.zst"} {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte("not compressed"), 0600))
+
+		_, err := readFixture(path)
+		assert.Error(t, err)
+		assert.False(t, os.IsNotExist(err))
+	}
+}
@@ -5,6 +5,18 @@
 // file". The actual response data will be byte compared with the golden file
 // and the test will fail if there is a difference.
 //
+// Golden files whose name ends with `.gz` or `.zst` are transparently
+// decompressed before the comparison and compressed again on update.
+//
+// Outputs that legitimately differ between Go releases or operating systems
+// can be stored in fixture variants, see WithFixtureVariants.
+//
+// Every assertion accepts options that apply to that call only, so a single
+// tester can be shared by tests that need different settings, including
+// parallel subtests:
+//
+//	g.AssertJSON(t, "user", user, golden.WithDiffEngine(golden.JSONDiff))
+//
 // Updating the golden file can be done by running `go test -update ./...`.
 package golden
 
@@ -13,8 +25,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"testing"
+	"text/template"
 	"time"
 
 	"github.com/pmezard/go-difflib/difflib"
@@ -52,6 +66,72 @@ const (
 	// defaultUseSubTestNameForDir sets the default value for the
 	// WithSubTestNameForDir option.
 	defaultUseSubTestNameForDir = false
+
+	// defaultUnorderedLines sets the default value for the
+	// WithUnorderedLines option.
+	defaultUnorderedLines = false
+
+	// defaultIgnoreWhitespace sets the default value for the
+	// WithIgnoreWhitespace option.
+	defaultIgnoreWhitespace = false
+
+	// defaultCompression sets the default value for the WithCompression
+	// option.
+	defaultCompression = NoCompression
+
+	// defaultContentHash sets the default value for the WithContentHash
+	// option.
+	defaultContentHash = false
+
+	// defaultProtoFormat sets the default value for the WithProtoFormat
+	// option.
+	defaultProtoFormat = ProtoText
+
+	// defaultPixelTolerance sets the default value for the
+	// WithPixelTolerance option.
+	defaultPixelTolerance = 0
+
+	// defaultMaxDiffPixelRatio sets the default value for the
+	// WithMaxDiffPixelRatio option.
+	defaultMaxDiffPixelRatio = 0
+
+	// defaultDBDumpFormat sets the default value for the WithDBDumpFormat
+	// option.
+	defaultDBDumpFormat = DBDumpText
+
+	// defaultLineEnding sets the default value for the WithLineEndings
+	// option.
+	defaultLineEnding = LF
+
+	// defaultTrailingNewline sets the default value for the
+	// WithTrailingNewline option.
+	defaultTrailingNewline = PreserveTrailingNewline
+
+	// defaultStripBOM sets the default value for the WithStripBOM option.
+	defaultStripBOM = false
+
+	// defaultDecodeUTF16 sets the default value for the WithUTF16Decoding
+	// option.
+	defaultDecodeUTF16 = false
+
+	// defaultMetadata sets the default value for the WithMetadata option.
+	defaultMetadata = false
+
+	// defaultCanonicalXML sets the default value for the WithCanonicalXML
+	// option.
+	defaultCanonicalXML = false
+
+	// defaultTemplateLeftDelim and defaultTemplateRightDelim set the default
+	// values for the WithTemplateDelims option.
+	defaultTemplateLeftDelim  = "{{"
+	defaultTemplateRightDelim = "}}"
+
+	// defaultGoAST sets the default value for the WithGoAST option.
+	defaultGoAST = false
+
+	// defaultTableFormat sets the default value for the WithTableFormat
+	// option.
+	defaultTableFormat = TableMarkdown
 )
 
 var (
@@ -82,8 +162,45 @@ type Golden struct {
 	diffEngine           DiffEngine
 	diffFn               DiffFn
 	ignoreTemplateErrors bool
+	templateFuncs        template.FuncMap
+	templateLeftDelim    string
+	templateRightDelim   string
 	useTestNameForDir    bool
 	useSubTestNameForDir bool
+
+	unorderedLines   bool
+	ignoreLines      []*regexp.Regexp
+	ignoreWhitespace bool
+
+	compression Compression
+
+	contentHash  bool
+	artifactsDir string
+
+	protoFormat ProtoFormat
+
+	pixelTolerance    uint8
+	maxDiffPixelRatio float64
+
+	dbDumpFormat  DBDumpFormat
+	maskedColumns []string
+
+	fixtureVariants []string
+
+	lineEnding      LineEnding
+	trailingNewline TrailingNewline
+	stripBOM        bool
+	decodeUTF16     bool
+
+	metadata    bool
+	contentType string
+
+	canonicalXML bool
+	goAST        bool
+
+	tableFormat  TableFormat
+	tableColumns []string
+	tableSortKey string
 }
 
 // === Create new testers ==================================
@@ -98,20 +215,61 @@ func New(t *testing.T, options ...Option) *Golden {
 		dirPerms:             defaultDirPerms,
 		diffEngine:           defaultDiffEngine,
 		ignoreTemplateErrors: defaultIgnoreTemplateErrors,
+		templateLeftDelim:    defaultTemplateLeftDelim,
+		templateRightDelim:   defaultTemplateRightDelim,
 		useTestNameForDir:    defaultUseTestNameForDir,
 		useSubTestNameForDir: defaultUseSubTestNameForDir,
+		unorderedLines:       defaultUnorderedLines,
+		ignoreWhitespace:     defaultIgnoreWhitespace,
+		compression:          defaultCompression,
+		contentHash:          defaultContentHash,
+		protoFormat:          defaultProtoFormat,
+		pixelTolerance:       defaultPixelTolerance,
+		maxDiffPixelRatio:    defaultMaxDiffPixelRatio,
+		dbDumpFormat:         defaultDBDumpFormat,
+		lineEnding:           defaultLineEnding,
+		trailingNewline:      defaultTrailingNewline,
+		stripBOM:             defaultStripBOM,
+		decodeUTF16:          defaultDecodeUTF16,
+		metadata:             defaultMetadata,
+		canonicalXML:         defaultCanonicalXML,
+		goAST:                defaultGoAST,
+		tableFormat:          defaultTableFormat,
+	}
+
+	g.apply(t, options)
+	return &g
+}
+
+// with returns a copy of the tester with the per-call options of an
+// assertion applied. The shared tester is never modified, so it can be used by
+// parallel tests. Without options the tester itself is returned.
+func (g *Golden) with(t *testing.T, options []Option) *Golden {
+	if len(options) == 0 {
+		return g
 	}
 
-	var err error
+	c := *g
+	// options append to these, which must not write to the shared arrays
+	c.ignoreLines = c.ignoreLines[:len(c.ignoreLines):len(c.ignoreLines)]
+	c.maskedColumns = c.maskedColumns[:len(c.maskedColumns):len(c.maskedColumns)]
+	c.fixtureVariants = c.fixtureVariants[:len(c.fixtureVariants):len(c.fixtureVariants)]
+
+	c.apply(t, options)
+	return &c
+}
+
+// apply applies the options to the tester. If there is an issue with applying
+// any of the options, an error will be reported and t.FailNow() will be
+// called.
+func (g *Golden) apply(t *testing.T, options []Option) {
+	t.Helper()
 	for _, option := range options {
-		err = option(&g)
-		if err != nil {
+		if err := option(g); err != nil {
 			t.Error(fmt.Errorf("could not apply option: %w", err))
 			t.FailNow()
 		}
 	}
-
-	return &g
 }
 
 // Diff generates a string that shows the difference between the actual and the
@@ -137,6 +295,9 @@ func Diff(engine DiffEngine, actual string, expected string) (diff string) {
 		dmp := diffmatchpatch.New()
 		diffs := dmp.DiffMain(actual, expected, false)
 		diff = dmp.DiffPrettyText(diffs)
+
+	case JSONDiff:
+		diff = jsonDiff(actual, expected)
 	}
 
 	return diff
@@ -148,13 +309,36 @@ func Diff(engine DiffEngine, actual string, expected string) (diff string) {
 // it can be explicitly called if needed. The more common approach would be to
 // update using `go test -update ./...`.
 func (g *Golden) Update(t *testing.T, name string, actualData []byte) error {
-	goldenFile := g.GoldenFileName(t, name)
+	if g.contentHash {
+		actualData = newDigest(actualData).bytes()
+	} else {
+		actualData = g.normalize(actualData)
+	}
+
+	target, err := g.updateTarget(t, name, actualData, g.GoldenFileName, g.normalize)
+	if err != nil || target == "" {
+		return err
+	}
+
+	if g.metadata {
+		actualData, err = g.withMetadata(t, target, actualData)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.updateFile(target, actualData)
+}
+
+// updateFile writes the data to the golden file, creating the fixture folder
+// if required.
+func (g *Golden) updateFile(goldenFile string, data []byte) error {
 	goldenFileDir := filepath.Dir(goldenFile)
 	if err := g.ensureDir(goldenFileDir); err != nil {
 		return err
 	}
 
-	if err := os.WriteFile(goldenFile, actualData, g.filePerms); err != nil {
+	if err := g.writeFixture(goldenFile, data); err != nil {
 		return err
 	}
 
@@ -175,10 +359,15 @@ func (g *Golden) ensureDir(loc string) error {
 		return os.MkdirAll(loc, g.dirPerms)
 
 	case err == nil && s.IsDir() && *clean && s.ModTime().UnixNano() != ts.UnixNano():
+		// the known mismatches are maintained by hand, so they survive cleaning
+		knownMismatches, readErr := os.ReadFile(filepath.Join(loc, KnownMismatchesFile))
 		if err := os.RemoveAll(loc); err != nil {
 			return err
 		}
-		return os.MkdirAll(loc, g.dirPerms)
+		if err := os.MkdirAll(loc, g.dirPerms); err != nil || readErr != nil {
+			return err
+		}
+		return os.WriteFile(filepath.Join(loc, KnownMismatchesFile), knownMismatches, g.filePerms)
 
 	case err == nil && !s.IsDir():
 		return newErrFixtureDirectoryIsFile(loc)
@@ -202,5 +391,10 @@ func (g *Golden) GoldenFileName(t *testing.T, name string) string {
 		}
 	}
 
-	return filepath.Join(dir, fmt.Sprintf("%s%s", name, g.fileNameSuffix))
+	file := fmt.Sprintf("%s%s", name, g.fileNameSuffix)
+	if suffix := g.compression.suffix(); !strings.HasSuffix(file, suffix) {
+		file += suffix
+	}
+
+	return filepath.Join(dir, file)
 }
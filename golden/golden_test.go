@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"testing"
 	"time"
 
@@ -300,3 +301,40 @@ func TestCleanFunction(t *testing.T) {
 	*clean = savedCleanState
 	*update = savedUpdateState
 }
+
+func TestPerCallOptions(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithIgnoreLines("^a"), WithMaskedColumns("id"))
+	require.NoError(t, g.Update(t, "text", []byte("text")))
+	require.NoError(t, New(t, WithFixtureDir(dir), WithNameSuffix(".json")).Update(t, "data", []byte("{}")))
+
+	t.Run("parallel", func(t *testing.T) {
+		t.Run("text", func(t *testing.T) {
+			t.Parallel()
+			g.Assert(t, "text", []byte("text"))
+		})
+		t.Run("json", func(t *testing.T) {
+			t.Parallel()
+			g.AssertJSON(t, "data", map[string]int{}, WithNameSuffix(".json"), WithDiffEngine(JSONDiff))
+		})
+		t.Run("ignored", func(t *testing.T) {
+			t.Parallel()
+			g.Assert(t, "text", []byte("text\nb"), WithIgnoreLines("^b"))
+		})
+	})
+
+	// the shared tester is not modified
+	assert.Equal(t, defaultFileNameSuffix, g.fileNameSuffix)
+	assert.Equal(t, defaultDiffEngine, g.diffEngine)
+	assert.Len(t, g.ignoreLines, 1)
+
+	// appending options do not write to the arrays of the shared tester
+	g.ignoreLines = append(make([]*regexp.Regexp, 0, 4), g.ignoreLines...)
+	c := g.with(t, []Option{WithIgnoreLines("^c")})
+	d := g.with(t, []Option{WithIgnoreLines("^d")})
+	assert.Equal(t, "^c", c.ignoreLines[1].String())
+	assert.Equal(t, "^d", d.ignoreLines[1].String())
+
+	// without options the tester itself is used
+	assert.Same(t, g, g.with(t, nil))
+}
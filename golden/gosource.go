@@ -0,0 +1,210 @@
+package golden
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"go/parser"
+	"go/printer"
+	"go/scanner"
+	"go/token"
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	// goSourceSuffix is appended to the name of the golden files of
+	// AssertGoSource, giving `<name>.go.golden` fixtures.
+	goSourceSuffix = ".go"
+
+	// goSourceContentType is the content type of the golden files of
+	// AssertGoSource.
+	goSourceContentType = "text/x-go; charset=utf-8"
+)
+
+// AssertGoSource formats the actual Go source with go/format and compares it
+// with the source in the golden files, so changes of the formatting of a code
+// generator do not cause whole file diffs. The golden files are named
+// `<name>.go.golden` and store the formatted source. If the update flag is
+// set, it will also update the golden file.
+//
+// The test fails with the location of the syntax error if the actual source
+// does not parse. With WithGoAST, comments and layout are ignored and only the
+// tokens of the sources are compared.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertGoSource(t *testing.T, name string, src []byte, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts).withContentType(goSourceContentType)
+	// only the file name gets the suffix, known mismatches and manifests
+	// refer to the name as passed
+	g.fileNameSuffix = goSourceSuffix + g.fileNameSuffix
+	formatted, err := formatGoSource(src)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if !g.goAST {
+		g.Assert(t, name, formatted)
+		return
+	}
+
+	if *update {
+		err := g.Update(t, name, formatted)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	report(t, g.conclude(t, name, g.GoldenFileName, g.compareGoAST(t, name, formatted)))
+}
+
+// formatGoSource formats the source. The first syntax error is reported with
+// its position and the offending line, the following ones are usually caused by
+// it.
+func formatGoSource(src []byte) ([]byte, error) {
+	formatted, err := format.Source(src)
+	if err == nil {
+		return formatted, nil
+	}
+
+	var list scanner.ErrorList
+	if !errors.As(err, &list) || len(list) == 0 {
+		return nil, fmt.Errorf("could not format the actual Go source: %w", err)
+	}
+
+	pos := list[0].Pos
+	line := sourceLine(src, pos.Line)
+	caret := []rune{}
+	for i, r := range line {
+		if i >= pos.Column-1 {
+			break
+		}
+		if r != '\t' {
+			r = ' '
+		}
+		caret = append(caret, r)
+	}
+
+	msg := fmt.Sprintf("actual Go source does not parse: %d:%d: %s", pos.Line, pos.Column, list[0].Msg)
+	return nil, fmt.Errorf("%s\n\n%s\n%s^", msg, line, string(caret))
+}
+
+// sourceLine returns the line of the source, starting at 1.
+func sourceLine(src []byte, line int) string {
+	lines := strings.Split(string(src), "\n")
+	if line < 1 || line > len(lines) {
+		return ""
+	}
+	return strings.TrimSuffix(lines[line-1], "\r")
+}
+
+// compareGoAST is reading the golden fixture file and compare the tokens of
+// the stored source with the tokens of the actual source.
+func (g *Golden) compareGoAST(t *testing.T, name string, actualData []byte) error {
+	expectedData, err := readFixture(g.resolveFixture(t, name, g.GoldenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newErrFixtureNotFound()
+		}
+
+		return fmt.Errorf("expected %s to be nil", err.Error())
+	}
+	expectedData = g.normalize(expectedData)
+
+	expectedTokens, err := goTokens(expectedData)
+	if err != nil {
+		return fmt.Errorf("could not parse the golden fixture as Go source: %w", err)
+	}
+	actualTokens, err := goTokens(actualData)
+	if err != nil {
+		return fmt.Errorf("could not parse the actual Go source: %w", err)
+	}
+	if equalStrings(actualTokens, expectedTokens) {
+		return nil
+	}
+
+	// the sources without comments give a diff of the relevant changes
+	actual, err := stripGoComments(actualData)
+	if err != nil {
+		return err
+	}
+	expected, err := stripGoComments(expectedData)
+	if err != nil {
+		return err
+	}
+	return g.newMismatch(actual, expected)
+}
+
+// goTokens returns the tokens of the source without comments. Semicolons and
+// trailing commas are dropped, they depend on the line breaks of the source.
+func goTokens(src []byte) ([]string, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var errs scanner.ErrorList
+	var s scanner.Scanner
+	s.Init(file, src, func(pos token.Position, msg string) { errs.Add(pos, msg) }, 0)
+
+	var tokens []string
+	comma := false
+	for {
+		_, tok, lit := s.Scan()
+		switch tok {
+		case token.EOF:
+			return tokens, errs.Err()
+		case token.SEMICOLON:
+			continue
+		case token.RPAREN, token.RBRACE, token.RBRACK:
+			comma = false
+		}
+
+		if comma {
+			tokens = append(tokens, token.COMMA.String())
+		}
+		comma = tok == token.COMMA
+		if comma {
+			continue
+		}
+
+		if lit == "" {
+			lit = tok.String()
+		}
+		tokens = append(tokens, lit)
+	}
+}
+
+// stripGoComments returns the formatted source without comments.
+func stripGoComments(src []byte) (string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	cfg := printer.Config{Mode: printer.UseSpaces | printer.TabIndent, Tabwidth: 8}
+	if err := cfg.Fprint(&buf, fset, file); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// equalStrings reports whether the slices have the same elements.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatGoSource(t *testing.T) {
+	formatted, err := formatGoSource([]byte("package p\nfunc  F( ) int{return 1}\n"))
+	require.NoError(t, err)
+	assert.Equal(t, "package p\n\nfunc F() int { return 1 }\n", string(formatted))
+
+	_, err = formatGoSource([]byte("package p\n\nfunc F() {\n\tx := ]\n}\n"))
+	assert.EqualError(t, err, "actual Go source does not parse: 4:7: expected operand, found ']'\n\n\tx := ]\n\t     ^")
+}
+
+func TestAssertGoSource(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertGoSource(t, "model", []byte("package model\ntype User struct{ID int}\n"))
+	*update = savedUpdateState
+
+	data, err := os.ReadFile(g.GoldenFileName(t, "model.go"))
+	require.NoError(t, err)
+	assert.Equal(t, "package model\n\ntype User struct{ ID int }\n", string(data))
+
+	g.AssertGoSource(t, "model", []byte("package model\n\ntype User struct{ID   int}"))
+}
+
+func TestCompareGoAST(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithGoAST(true), WithDiffEngine(Simple))
+
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "model.go"), []byte(""+
+		"// Package model is generated.\n"+
+		"package model\n"+
+		"\n"+
+		"// User is a user.\n"+
+		"type User struct {\n"+
+		"\tID int // the id\n"+
+		"}\n"), 0644))
+
+	// comments and layout do not matter
+	g.AssertGoSource(t, "model", []byte("package model\ntype User struct {\n\n\tID int\n}\n"))
+	g.AssertGoSource(t, "model", []byte("package model; type User struct{ ID int }"))
+
+	tokens, err := goTokens([]byte("f(a, b,\n)\nvar x = []int{1, 2}"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"f", "(", "a", ",", "b", ")", "var", "x", "=", "[", "]", "int", "{", "1", ",", "2", "}"}, tokens)
+
+	actual, err := formatGoSource([]byte("package model\ntype User struct { ID int64 }\n"))
+	require.NoError(t, err)
+	err = g.compareGoAST(t, "model.go", actual)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), "Expected: package model\n\ntype User struct {\n\tID int\n}\n")
+	assert.Contains(t, err.Error(), "Got: package model\n\ntype User struct{ ID int64 }\n")
+}
+
+func TestAssertGoSourceNames(t *testing.T) {
+	dir := t.TempDir()
+	out := t.TempDir()
+	savedManifest, savedManifestDir := manifest, *manifestDir
+	manifest, *manifestDir = newManifestWriter(), out
+	defer func() { manifest, *manifestDir = savedManifest, savedManifestDir }()
+
+	g := New(t, WithFixtureDir(dir))
+	fixture := filepath.Join(dir, "model.go.golden")
+	require.NoError(t, os.WriteFile(fixture, []byte("package model\n\ntype User struct{ ID int }\n"), 0644))
+
+	// the known mismatch is listed under the name passed to the assertion
+	require.NoError(t, os.WriteFile(filepath.Join(dir, KnownMismatchesFile), []byte("model 2999-12-31 regenerated soon\n"), 0644))
+	t.Run("assert", func(t *testing.T) {
+		g.AssertGoSource(t, "model", []byte("package model\ntype User struct{ ID int64 }\n"))
+	})
+
+	entries, err := os.ReadDir(out)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	m, err := ReadManifest(filepath.Join(out, entries[0].Name()))
+	require.NoError(t, err)
+	require.Len(t, m.Fixtures, 1)
+	assert.Equal(t, "model", m.Fixtures[0].Name)
+	assert.Equal(t, fixture, m.Fixtures[0].Path)
+	assert.Equal(t, OutcomeKnownMismatch, m.Fixtures[0].Outcome)
+}
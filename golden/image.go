@@ -0,0 +1,163 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"strings"
+	"testing"
+)
+
+const (
+	// imageFileSuffix is appended to the golden file name of image fixtures.
+	imageFileSuffix = ".png"
+
+	// imageDiffFileSuffix is appended to the golden file name of the visual
+	// diff that is written when an image does not match.
+	imageDiffFileSuffix = ".diff.png"
+)
+
+// AssertImage compares the actual image received with the expected image in
+// the golden files. If the update flag is set, it will also update the golden
+// file.
+//
+// The image is stored as a PNG fixture with the `.png` suffix appended to the
+// golden file name. Images are compared pixel by pixel, so differences in the
+// PNG encoding are not reported, and the line ending and encoding policy does
+// not apply. WithPixelTolerance and WithMaxDiffPixelRatio allow small
+// rendering differences. On mismatch, a visual diff highlighting the changed
+// pixels in red is written next to the fixture with the `.diff.png` suffix.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertImage(t *testing.T, name string, actualImage image.Image, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	if *update {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, actualImage); err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+
+		target, err := g.updateTarget(t, name, buf.Bytes(), g.ImageFileName, nil)
+		if err == nil && target != "" {
+			err = g.updateFile(target, buf.Bytes())
+		}
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	report(t, g.conclude(t, name, g.ImageFileName, g.compareImage(t, name, actualImage)))
+}
+
+// ImageFileName returns the file name of the image fixture.
+func (g *Golden) ImageFileName(t *testing.T, name string) string {
+	return strings.TrimSuffix(g.GoldenFileName(t, name), g.compression.suffix()) + imageFileSuffix
+}
+
+// imageDiffFileName returns the file name of the visual diff of the image
+// fixture.
+func imageDiffFileName(fixture string) string {
+	return strings.TrimSuffix(fixture, imageFileSuffix) + imageDiffFileSuffix
+}
+
+// compareImage is reading the image fixture file and compare the stored image
+// with the actual image.
+func (g *Golden) compareImage(t *testing.T, name string, actualImage image.Image) error {
+	fixture := g.resolveFixture(t, name, g.ImageFileName)
+	data, err := os.ReadFile(fixture)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newErrFixtureNotFound()
+		}
+
+		return fmt.Errorf("expected %s to be nil", err.Error())
+	}
+
+	expectedImage, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("could not decode the image fixture: %w", err)
+	}
+
+	diffFile := imageDiffFileName(fixture)
+	eb, ab := expectedImage.Bounds(), actualImage.Bounds()
+	if eb.Dx() != ab.Dx() || eb.Dy() != ab.Dy() {
+		return newErrFixtureMismatch(fmt.Sprintf(
+			"Result did not match the golden image.\n\nExpected size: %dx%d\nGot size: %dx%d",
+			eb.Dx(), eb.Dy(), ab.Dx(), ab.Dy(),
+		))
+	}
+
+	diffImage, diffPixels := diffImages(expectedImage, actualImage, g.pixelTolerance)
+	total := eb.Dx() * eb.Dy()
+	if diffPixels == 0 || (total > 0 && float64(diffPixels)/float64(total) <= g.maxDiffPixelRatio) {
+		if err := os.Remove(diffFile); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	msg := fmt.Sprintf(
+		"Result did not match the golden image.\n\n%d of %d pixels (%.2f%%) differ, allowed ratio is %.2f%%.\n",
+		diffPixels, total, 100*float64(diffPixels)/float64(total), 100*g.maxDiffPixelRatio,
+	)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, diffImage); err != nil {
+		return err
+	}
+	if err := os.WriteFile(diffFile, buf.Bytes(), g.filePerms); err != nil {
+		msg += fmt.Sprintf("\nThe visual diff could not be saved: %s\n", err)
+	} else {
+		msg += fmt.Sprintf("\nThe visual diff was saved to %s\n", diffFile)
+	}
+
+	return newErrFixtureMismatch(msg)
+}
+
+// diffImages compares two images of the same size pixel by pixel. A pixel
+// differs when any channel differs by more than the tolerance. It returns a
+// faded grayscale copy of the expected image with the differing pixels
+// painted red, and the number of differing pixels.
+func diffImages(expected, actual image.Image, tolerance uint8) (*image.NRGBA, int) {
+	eb, ab := expected.Bounds(), actual.Bounds()
+	diff := image.NewNRGBA(image.Rect(0, 0, eb.Dx(), eb.Dy()))
+	highlight := color.NRGBA{R: 0xff, A: 0xff}
+
+	count := 0
+	for y := 0; y < eb.Dy(); y++ {
+		for x := 0; x < eb.Dx(); x++ {
+			e := color.NRGBAModel.Convert(expected.At(eb.Min.X+x, eb.Min.Y+y)).(color.NRGBA)
+			a := color.NRGBAModel.Convert(actual.At(ab.Min.X+x, ab.Min.Y+y)).(color.NRGBA)
+
+			if channelDiffers(e.R, a.R, tolerance) || channelDiffers(e.G, a.G, tolerance) ||
+				channelDiffers(e.B, a.B, tolerance) || channelDiffers(e.A, a.A, tolerance) {
+				count++
+				diff.SetNRGBA(x, y, highlight)
+				continue
+			}
+
+			gray := color.GrayModel.Convert(e).(color.Gray)
+			faded := 0xff - (0xff-gray.Y)/4
+			diff.SetNRGBA(x, y, color.NRGBA{R: faded, G: faded, B: faded, A: 0xff})
+		}
+	}
+
+	return diff, count
+}
+
+// channelDiffers reports whether the color channels differ by more than the
+// tolerance.
+func channelDiffers(expected, actual, tolerance uint8) bool {
+	if expected > actual {
+		return expected-actual > tolerance
+	}
+	return actual-expected > tolerance
+}
@@ -0,0 +1,141 @@
+package golden
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestImage(fill color.NRGBA) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 10, 10))
+	for y := 0; y < 10; y++ {
+		for x := 0; x < 10; x++ {
+			img.SetNRGBA(x, y, fill)
+		}
+	}
+	return img
+}
+
+func TestImageFileName(t *testing.T) {
+	g := New(t, WithCompression(Gzip))
+	assert.Equal(t, filepath.Join(defaultFixtureDir, "chart.golden.png"), g.ImageFileName(t, "chart"))
+	assert.Equal(t, filepath.Join(defaultFixtureDir, "chart.golden.diff.png"), imageDiffFileName(g.ImageFileName(t, "chart")))
+}
+
+func TestCompareImage(t *testing.T) {
+	blue := color.NRGBA{B: 200, A: 0xff}
+	expected := newTestImage(blue)
+
+	tests := map[string]struct {
+		options []Option
+		actual  func() image.Image
+		err     error
+	}{
+		"identical": {
+			actual: func() image.Image { return newTestImage(blue) },
+		},
+		"different encoding of the same pixels": {
+			actual: func() image.Image {
+				img := image.NewRGBA(image.Rect(5, 5, 15, 15))
+				for y := 5; y < 15; y++ {
+					for x := 5; x < 15; x++ {
+						img.Set(x, y, blue)
+					}
+				}
+				return img
+			},
+		},
+		"within channel tolerance": {
+			options: []Option{WithPixelTolerance(3)},
+			actual:  func() image.Image { return newTestImage(color.NRGBA{B: 203, A: 0xff}) },
+		},
+		"outside channel tolerance": {
+			options: []Option{WithPixelTolerance(3)},
+			actual:  func() image.Image { return newTestImage(color.NRGBA{B: 204, A: 0xff}) },
+			err:     &errFixtureMismatch{},
+		},
+		"within max diff ratio": {
+			options: []Option{WithMaxDiffPixelRatio(0.01)},
+			actual: func() image.Image {
+				img := newTestImage(blue)
+				img.SetNRGBA(3, 3, color.NRGBA{R: 0xff, A: 0xff})
+				return img
+			},
+		},
+		"outside max diff ratio": {
+			options: []Option{WithMaxDiffPixelRatio(0.01)},
+			actual: func() image.Image {
+				img := newTestImage(blue)
+				img.SetNRGBA(3, 3, color.NRGBA{R: 0xff, A: 0xff})
+				img.SetNRGBA(4, 3, color.NRGBA{R: 0xff, A: 0xff})
+				return img
+			},
+			err: &errFixtureMismatch{},
+		},
+		"different size": {
+			actual: func() image.Image { return image.NewNRGBA(image.Rect(0, 0, 10, 11)) },
+			err:    &errFixtureMismatch{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			savedUpdateState := *update
+			*update = true
+			g.AssertImage(t, "image", expected)
+			*update = savedUpdateState
+			defer func() {
+				assert.Nil(t, os.RemoveAll(g.fixtureDir))
+			}()
+
+			err := g.compareImage(t, "image", test.actual())
+			assert.IsType(t, test.err, err)
+		})
+	}
+}
+
+func TestCompareImageWritesVisualDiff(t *testing.T) {
+	g := New(t)
+	expected := newTestImage(color.NRGBA{G: 0xff, A: 0xff})
+	require.NoError(t, os.MkdirAll(g.fixtureDir, g.dirPerms))
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	f, err := os.Create(g.ImageFileName(t, "diff"))
+	require.NoError(t, err)
+	require.NoError(t, png.Encode(f, expected))
+	require.NoError(t, f.Close())
+
+	actual := newTestImage(color.NRGBA{G: 0xff, A: 0xff})
+	actual.SetNRGBA(1, 2, color.NRGBA{A: 0xff})
+	err = g.compareImage(t, "diff", actual)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), "1 of 100 pixels")
+
+	diffFile, err := os.Open(imageDiffFileName(g.ImageFileName(t, "diff")))
+	require.NoError(t, err)
+	diff, err := png.Decode(diffFile)
+	require.NoError(t, diffFile.Close())
+	require.NoError(t, err)
+	assert.Equal(t, color.NRGBA{R: 0xff, A: 0xff}, color.NRGBAModel.Convert(diff.At(1, 2)))
+	assert.NotEqual(t, color.NRGBA{R: 0xff, A: 0xff}, color.NRGBAModel.Convert(diff.At(0, 0)))
+
+	// the stale visual diff is removed once the image matches again
+	assert.Nil(t, g.compareImage(t, "diff", expected))
+	_, err = os.Stat(imageDiffFileName(g.ImageFileName(t, "diff")))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWithMaxDiffPixelRatioOutOfRange(t *testing.T) {
+	g := &Golden{}
+	assert.Error(t, g.WithMaxDiffPixelRatio(1.5))
+	assert.Error(t, g.WithMaxDiffPixelRatio(-0.1))
+}
@@ -1,8 +1,14 @@
 package golden
 
 import (
+	"database/sql"
+	"image"
+	"io"
 	"os"
 	"testing"
+	"text/template"
+
+	"google.golang.org/protobuf/proto"
 )
 
 // Compile time assurance
@@ -15,10 +21,17 @@ type Option func(OptionProcessor) error
 
 // Tester defines the methods that any golden tester should support.
 type Tester interface {
-	Assert(t *testing.T, name string, actualData []byte)
-	AssertJSON(t *testing.T, name string, actualJSONData interface{})
-	AssertXML(t *testing.T, name string, actualXMLData interface{})
-	AssertWithTemplate(t *testing.T, name string, data interface{}, actualData []byte)
+	Assert(t *testing.T, name string, actualData []byte, opts ...Option)
+	AssertJSON(t *testing.T, name string, actualJSONData interface{}, opts ...Option)
+	AssertXML(t *testing.T, name string, actualXMLData interface{}, opts ...Option)
+	AssertHTML(t *testing.T, name string, actualHTML []byte, opts ...Option)
+	AssertWithTemplate(t *testing.T, name string, data interface{}, actualData []byte, opts ...Option)
+	AssertProto(t *testing.T, name string, actualMessage proto.Message, opts ...Option)
+	AssertImage(t *testing.T, name string, actualImage image.Image, opts ...Option)
+	AssertDB(t *testing.T, name string, db *sql.DB, queries []string, opts ...Option)
+	AssertReader(t *testing.T, name string, actual io.Reader, opts ...Option)
+	AssertTable(t *testing.T, name string, rows interface{}, opts ...Option)
+	AssertGoSource(t *testing.T, name string, src []byte, opts ...Option)
 	Update(t *testing.T, name string, actualData []byte) error
 	GoldenFileName(t *testing.T, name string) string
 }
@@ -57,6 +70,19 @@ const (
 	// Expected: <data>
 	// Got: <data>
 	Simple
+
+	// JSONDiff understands the structure of JSON documents. It prints the
+	// changed members with their ancestors and collapses unchanged siblings.
+	// If one of the values is not valid JSON, ClassicDiff is used instead.
+	//
+	//	  {
+	//	    "user": {
+	//	~     "city": "Berlin" => "Paris"
+	//	+     "phone": "123"
+	//	      ... 4 unchanged
+	//	    }
+	//	  }
+	JSONDiff
 )
 
 // OptionProcessor defines the functions that can be called to set values for
@@ -71,8 +97,34 @@ type OptionProcessor interface {
 	WithDiffEngine(engine DiffEngine) error
 	WithDiffFn(fn DiffFn) error
 	WithIgnoreTemplateErrors(ignoreErrors bool) error
+	WithTemplateFuncs(funcs template.FuncMap) error
+	WithTemplateDelims(left, right string) error
 	WithTestNameForDir(use bool) error
 	WithSubTestNameForDir(use bool) error
+	WithUnorderedLines(unordered bool) error
+	WithIgnoreLines(patterns ...string) error
+	WithIgnoreWhitespace(ignore bool) error
+	WithCompression(compression Compression) error
+	WithContentHash(use bool) error
+	WithArtifactsDir(dir string) error
+	WithProtoFormat(format ProtoFormat) error
+	WithPixelTolerance(tolerance uint8) error
+	WithMaxDiffPixelRatio(ratio float64) error
+	WithDBDumpFormat(format DBDumpFormat) error
+	WithMaskedColumns(columns ...string) error
+	WithFixtureVariants(variants ...string) error
+	WithLineEndings(lineEnding LineEnding) error
+	WithTrailingNewline(trailingNewline TrailingNewline) error
+	WithStripBOM(strip bool) error
+	WithUTF16Decoding(decode bool) error
+	WithMetadata(use bool) error
+
+	WithCanonicalXML(canonical bool) error
+	WithGoAST(use bool) error
+
+	WithTableFormat(format TableFormat) error
+	WithTableColumns(columns ...string) error
+	WithTableSortKey(column string) error
 }
 
 // === OptionProcessor ===============================
@@ -149,6 +201,33 @@ func WithIgnoreTemplateErrors(ignoreErrors bool) Option {
 	}
 }
 
+// WithTemplateFuncs adds functions to the templates of AssertWithTemplate,
+// on top of the built-in matchers. The matchers turn volatile values into
+// patterns:
+//
+//	{{ matches "^[0-9a-f-]{36}$" }} text matching the regular expression
+//	{{ any }}                       any text up to the end of the line
+//	{{ timeRFC3339 }}               a RFC 3339 timestamp
+//
+// noinspection GoUnusedExportedFunction
+func WithTemplateFuncs(funcs template.FuncMap) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTemplateFuncs(funcs)
+	}
+}
+
+// WithTemplateDelims sets the action delimiters of the templates of
+// AssertWithTemplate, for fixtures that contain `{{` themselves, like Helm
+// charts or Go templates.
+//
+// Defaults to `{{` and `}}`.
+// noinspection GoUnusedExportedFunction
+func WithTemplateDelims(left, right string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTemplateDelims(left, right)
+	}
+}
+
 // WithTestNameForDir will create a directory with the test's name in the
 // fixture directory to store all the golden files.
 //
@@ -171,3 +250,268 @@ func WithSubTestNameForDir(use bool) Option {
 		return o.WithSubTestNameForDir(use)
 	}
 }
+
+// WithUnorderedLines makes the comparison insensitive to the order of the
+// lines. The actual and expected data are compared as a multiset of lines,
+// which is useful for output produced by concurrent goroutines.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithUnorderedLines(unordered bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithUnorderedLines(unordered)
+	}
+}
+
+// WithIgnoreLines removes every line matching one of the regular expressions
+// from both the actual and the expected data before they are compared, e.g.
+// `^took [0-9]+ms$`.
+// noinspection GoUnusedExportedFunction
+func WithIgnoreLines(patterns ...string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithIgnoreLines(patterns...)
+	}
+}
+
+// WithIgnoreWhitespace makes the comparison insensitive to whitespace. Leading
+// and trailing whitespace is trimmed, inner runs of whitespace are collapsed
+// to a single space and blank lines are dropped.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithIgnoreWhitespace(ignore bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithIgnoreWhitespace(ignore)
+	}
+}
+
+// WithCompression sets the compression format of the golden files. The
+// matching suffix (`.gz` or `.zst`) is appended to the fixture name. Fixtures
+// named with one of these suffixes through WithNameSuffix are compressed
+// without this option as well.
+//
+// Default value is NoCompression.
+// noinspection GoUnusedExportedFunction
+func WithCompression(compression Compression) Option {
+	return func(o OptionProcessor) error {
+		return o.WithCompression(compression)
+	}
+}
+
+// WithContentHash stores only the SHA-256 digest, the length and a short
+// preview of the output in the golden file instead of the output itself. It's
+// meant for outputs that are too big to be committed. The assertion compares
+// the digests and, on mismatch, dumps the actual output to the artifacts
+// directory for inspection. The digest is taken from the raw output, so line
+// based comparison rules and the encoding and line ending options do not apply
+// in this mode.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithContentHash(use bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithContentHash(use)
+	}
+}
+
+// WithArtifactsDir sets the directory the actual output is dumped to when a
+// content hash fixture does not match.
+//
+// Defaults to `golden-artifacts` in os.TempDir().
+// noinspection GoUnusedExportedFunction
+func WithArtifactsDir(dir string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithArtifactsDir(dir)
+	}
+}
+
+// WithProtoFormat sets the format protocol buffer messages are stored in by
+// AssertProto.
+//
+// Default value is ProtoText.
+// noinspection GoUnusedExportedFunction
+func WithProtoFormat(format ProtoFormat) Option {
+	return func(o OptionProcessor) error {
+		return o.WithProtoFormat(format)
+	}
+}
+
+// WithPixelTolerance sets how much each color channel of a pixel may differ
+// before AssertImage considers the pixel as changed.
+//
+// Default value is 0.
+// noinspection GoUnusedExportedFunction
+func WithPixelTolerance(tolerance uint8) Option {
+	return func(o OptionProcessor) error {
+		return o.WithPixelTolerance(tolerance)
+	}
+}
+
+// WithMaxDiffPixelRatio sets the ratio of changed pixels, between 0 and 1,
+// that AssertImage accepts.
+//
+// Default value is 0.
+// noinspection GoUnusedExportedFunction
+func WithMaxDiffPixelRatio(ratio float64) Option {
+	return func(o OptionProcessor) error {
+		return o.WithMaxDiffPixelRatio(ratio)
+	}
+}
+
+// WithDBDumpFormat sets the format AssertDB dumps the database state in.
+//
+// Default value is DBDumpText.
+// noinspection GoUnusedExportedFunction
+func WithDBDumpFormat(format DBDumpFormat) Option {
+	return func(o OptionProcessor) error {
+		return o.WithDBDumpFormat(format)
+	}
+}
+
+// WithMaskedColumns sets the columns whose values are replaced by `<masked>`
+// in the dumps of AssertDB, e.g. `created_at` or generated ids. Column names
+// are matched case-insensitively.
+// noinspection GoUnusedExportedFunction
+func WithMaskedColumns(columns ...string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithMaskedColumns(columns...)
+	}
+}
+
+// WithFixtureVariants resolves the fixtures through a chain of variants, for
+// outputs that legitimately differ between Go releases, operating systems or
+// build tags. The variants are ordered from the most to the least specific.
+// With the variants `go1.21` and `linux`, the fixtures are resolved in the
+// order `name.go1.21.linux.golden`, `name.linux.golden` and `name.golden`.
+//
+// On update, the most specific variant is only written when the output
+// differs from the fixture it would otherwise fall back to.
+// noinspection GoUnusedExportedFunction
+func WithFixtureVariants(variants ...string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithFixtureVariants(variants...)
+	}
+}
+
+// WithLineEndings sets how line endings are handled. The policy is applied to
+// the actual and the expected data of all assertions, except AssertImage, and
+// to the data written by Update.
+//
+// Default value is LF.
+// noinspection GoUnusedExportedFunction
+func WithLineEndings(lineEnding LineEnding) Option {
+	return func(o OptionProcessor) error {
+		return o.WithLineEndings(lineEnding)
+	}
+}
+
+// WithTrailingNewline sets how a line ending at the end of the data is
+// handled, e.g. EnsureTrailingNewline for fixtures edited by tools that
+// always append one.
+//
+// Default value is PreserveTrailingNewline.
+// noinspection GoUnusedExportedFunction
+func WithTrailingNewline(trailingNewline TrailingNewline) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTrailingNewline(trailingNewline)
+	}
+}
+
+// WithStripBOM removes the UTF-8 byte order mark at the start of the actual
+// and the expected data.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithStripBOM(strip bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithStripBOM(strip)
+	}
+}
+
+// WithUTF16Decoding converts UTF-16 encoded data into UTF-8 before it is
+// compared or written. The byte order is detected from the byte order mark.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithUTF16Decoding(decode bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithUTF16Decoding(decode)
+	}
+}
+
+// WithMetadata writes a metadata header to the golden files on update. The
+// header records the test and package that produced the fixture, the Go
+// version, the time the content last changed, the content type and the
+// scrubbers that were applied. It's stripped before any comparison and can be
+// read with Metadata or ReadMetadata. LintFixtures uses it to find fixtures of
+// tests that no longer exist.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithMetadata(use bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithMetadata(use)
+	}
+}
+
+// WithCanonicalXML canonicalizes the documents of AssertXML, in the golden
+// files as well as the actual ones, before they are compared. Namespace
+// prefixes are renamed in order of first use, attributes are sorted and
+// comments and insignificant whitespace are dropped, so only differences of
+// the document content fail. Differences are reported by element path, e.g.
+// `/catalog/book[2]/@id`.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithCanonicalXML(canonical bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithCanonicalXML(canonical)
+	}
+}
+
+// WithGoAST makes AssertGoSource compare the tokens of the actual and the
+// golden source, so changes of comments, blank lines and line breaks do not
+// fail the test. The diff shows the sources without comments.
+//
+// Default value is false.
+// noinspection GoUnusedExportedFunction
+func WithGoAST(use bool) Option {
+	return func(o OptionProcessor) error {
+		return o.WithGoAST(use)
+	}
+}
+
+// WithTableFormat sets the format AssertTable renders the rows in, an aligned
+// Markdown table (TableMarkdown) or an aligned plain text table (TablePlain).
+//
+// Default value is TableMarkdown.
+// noinspection GoUnusedExportedFunction
+func WithTableFormat(format TableFormat) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTableFormat(format)
+	}
+}
+
+// WithTableColumns sets the columns AssertTable renders, in their order.
+// Columns are named after the json tag of struct fields or the field name,
+// and after the keys of maps.
+//
+// Default renders all columns.
+// noinspection GoUnusedExportedFunction
+func WithTableColumns(columns ...string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTableColumns(columns...)
+	}
+}
+
+// WithTableSortKey sorts the rows of AssertTable by the values of the column,
+// so the golden file does not depend on the order the rows were produced in.
+// The column does not need to be rendered. Numbers are sorted by value.
+//
+// Default keeps the order of the rows.
+// noinspection GoUnusedExportedFunction
+func WithTableSortKey(column string) Option {
+	return func(o OptionProcessor) error {
+		return o.WithTableSortKey(column)
+	}
+}
@@ -0,0 +1,276 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// jsonObject is a decoded JSON object that keeps the order of its members, so
+// the diff follows the layout of the documents.
+type jsonObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// jsonDiff returns a tree of the differences between the two JSON documents.
+// The changed members are shown with their ancestors, unchanged siblings are
+// collapsed. If one of the documents is not valid JSON, or the documents are
+// equal and only differ in member order or whitespace, the classic diff is
+// returned instead.
+func jsonDiff(actual string, expected string) string {
+	a, errA := parseOrderedJSON(actual)
+	e, errE := parseOrderedJSON(expected)
+	if errA != nil || errE != nil || equalJSON(e, a) {
+		return Diff(ClassicDiff, actual, expected)
+	}
+
+	w := &jsonDiffWriter{}
+	w.value("", e, a, 0)
+	return w.buf.String()
+}
+
+// parseOrderedJSON decodes a single JSON document.
+func parseOrderedJSON(data string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+
+	v, err := decodeOrderedJSON(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the JSON document")
+	}
+	return v, nil
+}
+
+// decodeOrderedJSON decodes the next JSON value.
+func decodeOrderedJSON(dec *json.Decoder) (interface{}, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		obj := &jsonObject{values: map[string]interface{}{}}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			k := key.(string)
+			if _, ok := obj.values[k]; !ok {
+				obj.keys = append(obj.keys, k)
+			}
+			obj.values[k] = value
+		}
+		_, err := dec.Token()
+		return obj, err
+
+	case json.Delim('['):
+		list := []interface{}{}
+		for dec.More() {
+			value, err := decodeOrderedJSON(dec)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		_, err := dec.Token()
+		return list, err
+	}
+
+	return token, nil
+}
+
+// jsonDiffWriter renders the diff tree. Every line starts with a marker:
+// ` ` for ancestors, `~` for changed, `+` for added and `-` for removed
+// members.
+type jsonDiffWriter struct {
+	buf bytes.Buffer
+}
+
+// line writes a line of the tree.
+func (w *jsonDiffWriter) line(marker byte, depth int, text string) {
+	w.buf.WriteByte(marker)
+	w.buf.WriteByte(' ')
+	w.buf.WriteString(strings.Repeat("  ", depth))
+	w.buf.WriteString(text)
+	w.buf.WriteByte('\n')
+}
+
+// unchanged writes the number of collapsed unchanged siblings.
+func (w *jsonDiffWriter) unchanged(depth int, count int) {
+	if count == 1 {
+		w.line(' ', depth, "... 1 unchanged")
+	} else if count > 1 {
+		w.line(' ', depth, fmt.Sprintf("... %d unchanged", count))
+	}
+}
+
+// value writes the difference of two values. label is the member name or
+// index the values are stored at, empty for the document.
+func (w *jsonDiffWriter) value(label string, expected, actual interface{}, depth int) {
+	if eo, ok := expected.(*jsonObject); ok {
+		if ao, ok := actual.(*jsonObject); ok {
+			w.line(' ', depth, label+"{")
+			w.object(eo, ao, depth+1)
+			w.line(' ', depth, "}")
+			return
+		}
+	}
+
+	if el, ok := expected.([]interface{}); ok {
+		if al, ok := actual.([]interface{}); ok {
+			w.line(' ', depth, label+"[")
+			w.list(el, al, depth+1)
+			w.line(' ', depth, "]")
+			return
+		}
+	}
+
+	w.line('~', depth, label+formatJSON(expected)+" => "+formatJSON(actual))
+}
+
+// object writes the differences of the members of two objects.
+func (w *jsonDiffWriter) object(expected, actual *jsonObject, depth int) {
+	keys := append([]string(nil), expected.keys...)
+	for _, key := range actual.keys {
+		if _, ok := expected.values[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	same := 0
+	for _, key := range keys {
+		label := formatJSON(key) + ": "
+		e, inExpected := expected.values[key]
+		a, inActual := actual.values[key]
+
+		if inExpected && inActual && equalJSON(e, a) {
+			same++
+			continue
+		}
+
+		w.unchanged(depth, same)
+		same = 0
+		switch {
+		case !inActual:
+			w.line('-', depth, label+formatJSON(e))
+		case !inExpected:
+			w.line('+', depth, label+formatJSON(a))
+		default:
+			w.value(label, e, a, depth)
+		}
+	}
+	w.unchanged(depth, same)
+}
+
+// list writes the differences of the elements of two arrays, compared by
+// index.
+func (w *jsonDiffWriter) list(expected, actual []interface{}, depth int) {
+	same := 0
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		label := fmt.Sprintf("[%d]: ", i)
+		if i < len(expected) && i < len(actual) && equalJSON(expected[i], actual[i]) {
+			same++
+			continue
+		}
+
+		w.unchanged(depth, same)
+		same = 0
+		switch {
+		case i >= len(actual):
+			w.line('-', depth, label+formatJSON(expected[i]))
+		case i >= len(expected):
+			w.line('+', depth, label+formatJSON(actual[i]))
+		default:
+			w.value(label, expected[i], actual[i], depth)
+		}
+	}
+	w.unchanged(depth, same)
+}
+
+// equalJSON reports whether two decoded values are equal. The order of object
+// members does not matter and numbers are compared by their literal.
+func equalJSON(a, b interface{}) bool {
+	switch a := a.(type) {
+	case *jsonObject:
+		b, ok := b.(*jsonObject)
+		if !ok || len(a.keys) != len(b.keys) {
+			return false
+		}
+		for key, value := range a.values {
+			other, ok := b.values[key]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equalJSON(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+
+	return formatJSON(a) == formatJSON(b)
+}
+
+// formatJSON renders a decoded value as compact JSON.
+func formatJSON(v interface{}) string {
+	var buf bytes.Buffer
+	writeCompactJSON(&buf, v)
+	return buf.String()
+}
+
+// writeCompactJSON renders a decoded value as compact JSON, keeping the order
+// of the object members.
+func writeCompactJSON(buf *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case *jsonObject:
+		buf.WriteByte('{')
+		for i, key := range v.keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCompactJSON(buf, key)
+			buf.WriteByte(':')
+			writeCompactJSON(buf, v.values[key])
+		}
+		buf.WriteByte('}')
+
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, elem := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeCompactJSON(buf, elem)
+		}
+		buf.WriteByte(']')
+
+	default:
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		_ = enc.Encode(v)
+		buf.Truncate(buf.Len() - 1) // Encode appends a newline
+	}
+}
@@ -0,0 +1,90 @@
+package golden
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONDiff(t *testing.T) {
+	expected := `{
+  "id": 1,
+  "name": "gopher",
+  "user": {
+    "address": {
+      "street": "Main St",
+      "zip": "10115",
+      "city": "Berlin"
+    },
+    "fax": "456",
+    "email": "gopher@example.com",
+    "tags": ["a", "b", "c"]
+  },
+  "active": true
+}`
+	actual := `{
+  "id": 1,
+  "name": "gopher",
+  "user": {
+    "address": {
+      "street": "Main St",
+      "zip": "10115",
+      "city": "Paris"
+    },
+    "email": "gopher@example.com",
+    "tags": ["a", "x", "c", "d"],
+    "phone": "<123>"
+  },
+  "active": true
+}`
+
+	assert.Equal(t, ""+
+		"  {\n"+
+		"    ... 2 unchanged\n"+
+		`    "user": {`+"\n"+
+		`      "address": {`+"\n"+
+		"        ... 2 unchanged\n"+
+		`~       "city": "Berlin" => "Paris"`+"\n"+
+		"      }\n"+
+		`-     "fax": "456"`+"\n"+
+		"      ... 1 unchanged\n"+
+		`      "tags": [`+"\n"+
+		"        ... 1 unchanged\n"+
+		`~       [1]: "b" => "x"`+"\n"+
+		"        ... 1 unchanged\n"+
+		`+       [3]: "d"`+"\n"+
+		"      ]\n"+
+		`+     "phone": "<123>"`+"\n"+
+		"    }\n"+
+		"    ... 1 unchanged\n"+
+		"  }\n", Diff(JSONDiff, actual, expected))
+}
+
+func TestJSONDiffTypeChange(t *testing.T) {
+	assert.Equal(t, ""+
+		"  {\n"+
+		`~   "a": {"b":1} => [1,2.50]`+"\n"+
+		"  }\n",
+		Diff(JSONDiff, `{"a": [1, 2.50]}`, `{"a": {"b": 1}}`))
+
+	assert.Equal(t, "~ 1 => \"1\"\n", Diff(JSONDiff, `"1"`, `1`))
+}
+
+func TestJSONDiffMemberOrder(t *testing.T) {
+	assert.Equal(t, ""+
+		"  {\n"+
+		"    ... 1 unchanged\n"+
+		`~   "b": 2 => 3`+"\n"+
+		"  }\n",
+		Diff(JSONDiff, `{"a": {"y": 1, "x": 2}, "b": 3}`, `{"a": {"x": 2, "y": 1}, "b": 2}`))
+}
+
+func TestJSONDiffFallsBackToClassicDiff(t *testing.T) {
+	assert.Equal(t, Diff(ClassicDiff, "not json\n", "{}\n"), Diff(JSONDiff, "not json\n", "{}\n"))
+	assert.Equal(t, Diff(ClassicDiff, "{} {}\n", "{}\n"), Diff(JSONDiff, "{} {}\n", "{}\n"))
+
+	// equal documents that only differ in their text have no semantic diff
+	actual, expected := "{\"b\": 1, \"a\": 2}\n", "{\"a\": 2, \"b\": 1}\n"
+	assert.Equal(t, Diff(ClassicDiff, actual, expected), Diff(JSONDiff, actual, expected))
+	assert.NotContains(t, Diff(JSONDiff, actual, expected), "unchanged")
+}
@@ -0,0 +1,72 @@
+package golden
+
+import (
+	"bytes"
+	"sort"
+)
+
+// hasLineRules reports whether any line based comparison rule is enabled.
+func (g *Golden) hasLineRules() bool {
+	return g.unorderedLines || g.ignoreWhitespace || len(g.ignoreLines) > 0
+}
+
+// applyLineRules rewrites the data according to the line based comparison
+// rules (ignored lines, whitespace insensitivity and line order). The same
+// rules are applied to the actual and the expected data, so the diff that is
+// generated from the result only shows the meaningful differences.
+func (g *Golden) applyLineRules(data []byte) []byte {
+	if !g.hasLineRules() || len(data) == 0 {
+		return data
+	}
+
+	kept := g.filterLines(data)
+	if g.unorderedLines {
+		sort.SliceStable(kept, func(i, j int) bool {
+			return bytes.Compare(kept[i], kept[j]) < 0
+		})
+	}
+
+	return joinLines(kept)
+}
+
+// filterLines splits the data into lines, drops the ignored lines and
+// collapses the whitespace if the rules say so. The order is kept.
+func (g *Golden) filterLines(data []byte) [][]byte {
+	if len(data) == 0 {
+		return nil
+	}
+
+	lines := bytes.Split(bytes.TrimSuffix(normalizeLF(data), []byte{'\n'}), []byte{'\n'})
+	kept := make([][]byte, 0, len(lines))
+	for _, line := range lines {
+		if g.isIgnoredLine(line) {
+			continue
+		}
+		if g.ignoreWhitespace {
+			line = bytes.Join(bytes.Fields(line), []byte{' '})
+			if len(line) == 0 {
+				continue
+			}
+		}
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// joinLines joins the lines, each terminated by a line feed.
+func joinLines(lines [][]byte) []byte {
+	if len(lines) == 0 {
+		return []byte{}
+	}
+	return append(bytes.Join(lines, []byte{'\n'}), '\n')
+}
+
+// isIgnoredLine reports whether the line matches one of the ignore patterns.
+func (g *Golden) isIgnoredLine(line []byte) bool {
+	for _, re := range g.ignoreLines {
+		if re.Match(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package golden
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyLineRules(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		input    string
+		expected string
+	}{
+		"no rules keeps data as is": {
+			input:    "b\r\na  \n",
+			expected: "b\r\na  \n",
+		},
+		"unordered lines": {
+			options:  []Option{WithUnorderedLines(true)},
+			input:    "worker 2 done\nworker 1 done\nworker 3 done",
+			expected: "worker 1 done\nworker 2 done\nworker 3 done\n",
+		},
+		"ignore lines": {
+			options:  []Option{WithIgnoreLines(`^took [0-9]+ms$`, `^DEBUG`)},
+			input:    "start\ntook 32ms\nDEBUG cache hit\nend\n",
+			expected: "start\nend\n",
+		},
+		"ignore whitespace": {
+			options:  []Option{WithIgnoreWhitespace(true)},
+			input:    "  key =\t value  \n\n\tother   line\r\n",
+			expected: "key = value\nother line\n",
+		},
+		"all lines ignored": {
+			options:  []Option{WithIgnoreLines(`.*`)},
+			input:    "a\nb\n",
+			expected: "",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			assert.Equal(t, test.expected, string(g.applyLineRules([]byte(test.input))))
+		})
+	}
+}
+
+func TestCompareWithLineRules(t *testing.T) {
+	tests := map[string]struct {
+		options  []Option
+		expected string
+		actual   string
+		err      error
+		diff     string
+	}{
+		"unordered lines match": {
+			options:  []Option{WithUnorderedLines(true)},
+			expected: "a\nb\nc\n",
+			actual:   "c\na\nb\n",
+			err:      nil,
+		},
+		"unordered lines detect multiset difference": {
+			options:  []Option{WithUnorderedLines(true)},
+			expected: "a\nb\nb\n",
+			actual:   "b\na\na\n",
+			err:      &errFixtureMismatch{},
+			diff:     "+a",
+		},
+		"ignored lines do not show up in the diff": {
+			options:  []Option{WithIgnoreLines(`^took`)},
+			expected: "begin\ntook 10ms\nend\n",
+			actual:   "begin\ntook 32ms\nfinish\n",
+			err:      &errFixtureMismatch{},
+			diff:     "+finish",
+		},
+		"whitespace differences are ignored": {
+			options:  []Option{WithIgnoreWhitespace(true)},
+			expected: "a  b\n",
+			actual:   "a b   \n\n",
+			err:      nil,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			g := New(t, test.options...)
+			require.NoError(t, g.Update(t, "lines", []byte(test.expected)))
+			defer func() {
+				assert.Nil(t, os.RemoveAll(g.fixtureDir))
+			}()
+
+			err := g.compare(t, "lines", []byte(test.actual))
+			assert.IsType(t, test.err, err)
+			if err != nil {
+				assert.Contains(t, err.Error(), test.diff)
+				assert.NotContains(t, err.Error(), "took")
+			}
+
+			err = g.compareTemplate(t, "lines", nil, []byte(test.actual))
+			assert.IsType(t, test.err, err)
+		})
+	}
+}
+
+func TestWithIgnoreLinesInvalidPattern(t *testing.T) {
+	g := &Golden{}
+	err := g.WithIgnoreLines(`(`)
+	require.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "invalid ignore line pattern"))
+}
@@ -0,0 +1,258 @@
+package golden
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Outcome is the result of an assertion recorded in the manifest.
+type Outcome string
+
+// noinspection GoUnusedConst
+const (
+	// OutcomePass means the actual data matched the golden file.
+	OutcomePass Outcome = "pass"
+
+	// OutcomeUpdated means the golden file was updated with `-update`.
+	OutcomeUpdated Outcome = "updated"
+
+	// OutcomeFail means the actual data did not match the golden file.
+	OutcomeFail Outcome = "fail"
+
+	// OutcomeKnownMismatch means the mismatch was accepted because it's listed
+	// in the known mismatches.
+	OutcomeKnownMismatch Outcome = "known-mismatch"
+
+	// OutcomeMissing means the golden file does not exist.
+	OutcomeMissing Outcome = "missing"
+
+	// OutcomeError means the golden file could not be compared.
+	OutcomeError Outcome = "error"
+)
+
+// manifestDir is the directory the manifests are written to. It's set with
+// `go test ./... -golden-manifest=<dir>`.
+var manifestDir = flag.String("golden-manifest", "", "Write a manifest of the used golden files to this directory")
+
+// Manifest lists the golden files used by the tests of a package run. With
+// `-golden-manifest=<dir>`, every package writes its manifest to
+// `<dir>/<import path with / replaced by _>.json`.
+type Manifest struct {
+	// Package is the import path of the package of the tests.
+	Package string `json:"package"`
+	// Dir is the directory of the package, the golden file paths are relative
+	// to it.
+	Dir string `json:"dir"`
+	// Fixtures are the golden files used by the tests, sorted by test and
+	// path.
+	Fixtures []ManifestEntry `json:"fixtures"`
+}
+
+// ManifestEntry records the use of a golden file by a test. If a test uses a
+// golden file several times, the last outcome is recorded.
+type ManifestEntry struct {
+	// Test is the name of the test, including subtests.
+	Test string `json:"test"`
+	// Name is the name passed to the assertion.
+	Name string `json:"name"`
+	// Path is the path of the golden file.
+	Path string `json:"path"`
+	// Size is the size of the golden file in bytes, 0 if it does not exist.
+	Size int64 `json:"size"`
+	// Outcome is the result of the assertion.
+	Outcome Outcome `json:"outcome"`
+}
+
+// manifestWriter collects the manifest entries of the test run in memory and
+// writes them when a test that recorded entries finishes.
+type manifestWriter struct {
+	mu       sync.Mutex
+	manifest Manifest
+	index    map[[2]string]int
+	// dirty is set if entries were recorded since the last write.
+	dirty bool
+	// pending are the tests whose cleanup writes the manifest.
+	pending map[string]bool
+}
+
+// newManifestWriter returns an empty manifestWriter.
+func newManifestWriter() *manifestWriter {
+	return &manifestWriter{index: map[[2]string]int{}, pending: map[string]bool{}}
+}
+
+// manifest is the manifest of the test run.
+var manifest = newManifestWriter()
+
+// ReadManifest reads a manifest written with `-golden-manifest`.
+func ReadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// conclude applies the known mismatches to the result of a comparison and
+// records the outcome in the manifest if one is requested.
+func (g *Golden) conclude(t *testing.T, name string, fileName fileNameFn, err error) error {
+	t.Helper()
+	result := g.acceptKnownMismatch(t, name, err)
+	if *manifestDir == "" {
+		return result
+	}
+
+	entry := ManifestEntry{
+		Test:    t.Name(),
+		Name:    name,
+		Path:    g.resolveFixture(t, name, fileName),
+		Outcome: outcomeOf(err, result),
+	}
+	if info, statErr := os.Stat(entry.Path); statErr == nil {
+		entry.Size = info.Size()
+	}
+
+	if writeErr := manifest.record(t, *manifestDir, testPackage(t), entry); writeErr != nil {
+		t.Logf("WARNING: could not write the golden manifest: %s", writeErr)
+	}
+	return result
+}
+
+// outcomeOf returns the outcome of a comparison that returned err, which the
+// known mismatches turned into result.
+func outcomeOf(err error, result error) Outcome {
+	var notFound *errFixtureNotFound
+	var mismatch *errFixtureMismatch
+
+	switch {
+	case err == nil && *update:
+		return OutcomeUpdated
+	case err == nil:
+		return OutcomePass
+	case errors.As(err, &notFound):
+		return OutcomeMissing
+	case !errors.As(err, &mismatch):
+		return OutcomeError
+	case result == nil:
+		return OutcomeKnownMismatch
+	}
+	return OutcomeFail
+}
+
+// record adds the entry to the manifest. The manifest file is written when
+// the test finishes, unless a test containing it writes it already, so a
+// package is not rewritten for every assertion.
+func (w *manifestWriter) record(t *testing.T, dir string, pkg string, entry ManifestEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.manifest.Dir == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		w.manifest.Package, w.manifest.Dir = pkg, wd
+	}
+
+	if rel, err := filepath.Rel(w.manifest.Dir, entry.Path); err == nil && filepath.IsAbs(entry.Path) && !strings.HasPrefix(rel, "..") {
+		entry.Path = rel
+	}
+	entry.Path = filepath.ToSlash(entry.Path)
+
+	key := [2]string{entry.Test, entry.Path}
+	if i, ok := w.index[key]; ok {
+		w.manifest.Fixtures[i] = entry
+	} else {
+		w.index[key] = len(w.manifest.Fixtures)
+		w.manifest.Fixtures = append(w.manifest.Fixtures, entry)
+	}
+	w.dirty = true
+
+	for test := range w.pending {
+		if t.Name() == test || strings.HasPrefix(t.Name(), test+"/") {
+			return nil
+		}
+	}
+	w.pending[t.Name()] = true
+	t.Cleanup(func() {
+		if err := w.flush(dir, t.Name()); err != nil {
+			t.Logf("WARNING: could not write the golden manifest: %s", err)
+		}
+	})
+	return nil
+}
+
+// flush writes the manifest if entries were recorded since the last write.
+func (w *manifestWriter) flush(dir string, test string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delete(w.pending, test)
+	if !w.dirty {
+		return nil
+	}
+	w.dirty = false
+	return w.write(dir)
+}
+
+// write writes the manifest to the directory, replacing the previous version
+// atomically.
+func (w *manifestWriter) write(dir string) error {
+	sorted := w.manifest
+	sorted.Fixtures = append([]ManifestEntry(nil), w.manifest.Fixtures...)
+	sort.SliceStable(sorted.Fixtures, func(i, j int) bool {
+		a, b := sorted.Fixtures[i], sorted.Fixtures[j]
+		if a.Test != b.Test {
+			return a.Test < b.Test
+		}
+		return a.Path < b.Path
+	})
+
+	data, err := json.MarshalIndent(sorted, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, defaultDirPerms); err != nil {
+		return err
+	}
+
+	file := filepath.Join(dir, manifestFileName(sorted.Package))
+	tmp, err := os.CreateTemp(dir, ".golden-manifest-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), defaultFilePerms); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), file)
+}
+
+// manifestFileName returns the name of the manifest of a package.
+func manifestFileName(pkg string) string {
+	if pkg == "" {
+		pkg = "unknown"
+	}
+	return strings.NewReplacer("/", "_", `\`, "_").Replace(pkg) + ".json"
+}
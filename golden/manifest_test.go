@@ -0,0 +1,86 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifest(t *testing.T) {
+	out := t.TempDir()
+	savedManifest, savedManifestDir := manifest, *manifestDir
+	manifest, *manifestDir = newManifestWriter(), out
+	defer func() { manifest, *manifestDir = savedManifest, savedManifestDir }()
+
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "hello"), []byte("hello"), 0644))
+	file := filepath.Join(out, "github.com_nao1215_gorky_golden.json")
+
+	t.Run("run", func(t *testing.T) {
+		g.Assert(t, "hello", []byte("hello"))
+		assert.Error(t, g.conclude(t, "hello", g.GoldenFileName, g.compare(t, "hello", []byte("bye"))))
+		t.Run("sub", func(t *testing.T) {
+			assert.Error(t, g.conclude(t, "missing", g.GoldenFileName, g.compare(t, "missing", nil)))
+		})
+
+		// the manifest is written once the outermost recording test finishes
+		assert.NoFileExists(t, file)
+	})
+
+	m, err := ReadManifest(file)
+	require.NoError(t, err)
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	assert.Equal(t, &Manifest{
+		Package: "github.com/nao1215/gorky/golden",
+		Dir:     wd,
+		Fixtures: []ManifestEntry{
+			{Test: "TestManifest/run", Name: "hello", Path: filepath.ToSlash(g.GoldenFileName(t, "hello")), Size: 5, Outcome: OutcomeFail},
+			{Test: "TestManifest/run/sub", Name: "missing", Path: filepath.ToSlash(g.GoldenFileName(t, "missing")), Outcome: OutcomeMissing},
+		},
+	}, m)
+}
+
+func TestManifestAssertReaderUpdate(t *testing.T) {
+	out := t.TempDir()
+	savedManifest, savedManifestDir := manifest, *manifestDir
+	manifest, *manifestDir = newManifestWriter(), out
+	defer func() { manifest, *manifestDir = savedManifest, savedManifestDir }()
+
+	g := New(t, WithFixtureDir(t.TempDir()))
+	t.Run("update", func(t *testing.T) {
+		savedUpdateState := *update
+		*update = true
+		defer func() { *update = savedUpdateState }()
+		g.AssertReader(t, "stream", strings.NewReader("streamed"))
+	})
+
+	m, err := ReadManifest(filepath.Join(out, "github.com_nao1215_gorky_golden.json"))
+	require.NoError(t, err)
+	require.Len(t, m.Fixtures, 1)
+	assert.Equal(t, "stream", m.Fixtures[0].Name)
+	assert.Equal(t, int64(len("streamed")), m.Fixtures[0].Size)
+	assert.Equal(t, OutcomeUpdated, m.Fixtures[0].Outcome)
+}
+
+func TestOutcomeOf(t *testing.T) {
+	mismatch := newErrFixtureMismatch("diff")
+	assert.Equal(t, OutcomePass, outcomeOf(nil, nil))
+	assert.Equal(t, OutcomeFail, outcomeOf(mismatch, mismatch))
+	assert.Equal(t, OutcomeKnownMismatch, outcomeOf(mismatch, nil))
+	assert.Equal(t, OutcomeMissing, outcomeOf(newErrFixtureNotFound(), newErrFixtureNotFound()))
+	assert.Equal(t, OutcomeError, outcomeOf(os.ErrPermission, os.ErrPermission))
+
+	savedUpdateState := *update
+	*update = true
+	assert.Equal(t, OutcomeUpdated, outcomeOf(nil, nil))
+	*update = savedUpdateState
+
+	assert.Equal(t, "unknown.json", manifestFileName(""))
+}
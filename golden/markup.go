@@ -0,0 +1,556 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+// xmlNamespaceURL is the namespace bound to the reserved `xml` prefix.
+const xmlNamespaceURL = "http://www.w3.org/XML/1998/namespace"
+
+// markupNode is an element or a text node of a canonicalized XML or HTML
+// document. The document itself is an element without name.
+type markupNode struct {
+	// name is the element name, empty for text nodes and the document.
+	name string
+	// attrs are the attributes sorted by name.
+	attrs []markupAttr
+	// text is the content of text nodes.
+	text string
+	// children are the child elements and text nodes.
+	children []*markupNode
+	// inline elements are rendered on a single line, as whitespace in them
+	// is significant (e.g. <pre>).
+	inline bool
+	// raw elements contain text that must not be escaped (e.g. <script>).
+	raw bool
+}
+
+// markupAttr is an attribute of a markupNode.
+type markupAttr struct {
+	name  string
+	value string
+}
+
+// isText reports whether the node is a text node. Empty text nodes are never
+// created.
+func (n *markupNode) isText() bool {
+	return n.name == "" && n.text != ""
+}
+
+// markupFormat describes how a markup language is canonicalized.
+type markupFormat struct {
+	name        string
+	contentType string
+	parse       func(data []byte) (*markupNode, error)
+	render      func(doc *markupNode) []byte
+}
+
+var (
+	// xmlMarkup canonicalizes XML documents, see WithCanonicalXML.
+	xmlMarkup = markupFormat{
+		name:        "XML",
+		contentType: "application/xml",
+		parse:       parseCanonicalXML,
+		render:      renderCanonicalXML,
+	}
+
+	// htmlMarkup normalizes HTML documents, see AssertHTML.
+	htmlMarkup = markupFormat{
+		name:        "HTML",
+		contentType: "text/html; charset=utf-8",
+		parse:       parseNormalizedHTML,
+		render:      renderNormalizedHTML,
+	}
+)
+
+// AssertHTML normalizes the actual HTML document or fragment and compares it
+// with the normalized HTML in the golden files. If the update flag is set, it
+// will also update the golden file with the normalized HTML.
+//
+// Both sides are re-serialized deterministically: attributes are sorted,
+// class names are sorted, comments are dropped and whitespace is collapsed
+// outside of <pre> and <textarea>. Fragments are wrapped in <html> and <body>
+// like a browser does. On mismatch, the differences are reported by element
+// path, e.g. `/html/body/div[2]/@class`.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertHTML(t *testing.T, name string, actualHTML []byte, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	report(t, g.checkMarkup(t, name, actualHTML, htmlMarkup))
+}
+
+// checkMarkup is the counterpart of check for canonicalized documents.
+func (g *Golden) checkMarkup(t *testing.T, name string, actualData []byte, format markupFormat) error {
+	actual, err := format.parse(g.normalize(actualData))
+	if err != nil {
+		return fmt.Errorf("could not parse the actual %s: %w", format.name, err)
+	}
+
+	if *update {
+		if err := g.withContentType(format.contentType).Update(t, name, format.render(actual)); err != nil {
+			return err
+		}
+	}
+
+	return g.conclude(t, name, g.GoldenFileName, g.compareMarkup(t, name, actual, format))
+}
+
+// compareMarkup is reading the golden fixture file and compare the stored
+// document with the actual document.
+func (g *Golden) compareMarkup(t *testing.T, name string, actual *markupNode, format markupFormat) error {
+	data, err := readFixture(g.resolveFixture(t, name, g.GoldenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newErrFixtureNotFound()
+		}
+
+		return fmt.Errorf("expected %s to be nil", err.Error())
+	}
+
+	expected, err := format.parse(g.normalize(data))
+	if err != nil {
+		return fmt.Errorf("could not parse the golden fixture as %s: %w", format.name, err)
+	}
+
+	actualData, expectedData := format.render(actual), format.render(expected)
+	if bytes.Equal(actualData, expectedData) {
+		return nil
+	}
+
+	diffs := diffMarkup("", expected, actual)
+	if len(diffs) == 0 {
+		// only the order of differently named siblings changed
+		return g.newMismatch(string(actualData), string(expectedData))
+	}
+
+	msg := "Result did not match the golden fixture. Differences are below:\n\n"
+	msg += strings.Join(diffs, "\n")
+	return newErrFixtureMismatch(msg)
+}
+
+// diffMarkup returns the differences between the two elements, one line per
+// element, attribute or text path. Children are matched by name and position
+// among the siblings of the same name.
+func diffMarkup(path string, expected, actual *markupNode) []string {
+	var diffs []string
+
+	expectedAttrs, actualAttrs := map[string]string{}, map[string]string{}
+	var attrNames []string
+	for _, attr := range expected.attrs {
+		expectedAttrs[attr.name] = attr.value
+		attrNames = append(attrNames, attr.name)
+	}
+	for _, attr := range actual.attrs {
+		actualAttrs[attr.name] = attr.value
+		if _, ok := expectedAttrs[attr.name]; !ok {
+			attrNames = append(attrNames, attr.name)
+		}
+	}
+	sort.Strings(attrNames)
+
+	for _, attr := range attrNames {
+		attrPath := path + "/@" + attr
+		e, inExpected := expectedAttrs[attr]
+		a, inActual := actualAttrs[attr]
+		switch {
+		case !inActual:
+			diffs = append(diffs, fmt.Sprintf("- %s: %q", attrPath, e))
+		case !inExpected:
+			diffs = append(diffs, fmt.Sprintf("+ %s: %q", attrPath, a))
+		case e != a:
+			diffs = append(diffs, fmt.Sprintf("~ %s: %q => %q", attrPath, e, a))
+		}
+	}
+
+	expectedChildren, keys := groupMarkupChildren(expected.children, nil)
+	actualChildren, keys := groupMarkupChildren(actual.children, keys)
+
+	for _, key := range keys {
+		e, a := expectedChildren[key], actualChildren[key]
+		for i := 0; i < len(e) || i < len(a); i++ {
+			childPath := path + "/" + key
+			if len(e) > 1 || len(a) > 1 {
+				childPath += fmt.Sprintf("[%d]", i+1)
+			}
+
+			switch {
+			case i >= len(a):
+				diffs = append(diffs, "- "+describeMarkup(childPath, e[i]))
+			case i >= len(e):
+				diffs = append(diffs, "+ "+describeMarkup(childPath, a[i]))
+			case e[i].isText():
+				if e[i].text != a[i].text {
+					diffs = append(diffs, fmt.Sprintf("~ %s: %q => %q", childPath, e[i].text, a[i].text))
+				}
+			default:
+				diffs = append(diffs, diffMarkup(childPath, e[i], a[i])...)
+			}
+		}
+	}
+
+	return diffs
+}
+
+// groupMarkupChildren groups the children by their path key, the element name
+// or `text()`. New keys are appended to keys in document order.
+func groupMarkupChildren(children []*markupNode, keys []string) (map[string][]*markupNode, []string) {
+	known := map[string]bool{}
+	for _, key := range keys {
+		known[key] = true
+	}
+
+	groups := map[string][]*markupNode{}
+	for _, child := range children {
+		key := child.name
+		if child.isText() {
+			key = "text()"
+		}
+		if !known[key] {
+			known[key] = true
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], child)
+	}
+	return groups, keys
+}
+
+// describeMarkup describes an added or removed node.
+func describeMarkup(path string, n *markupNode) string {
+	if n.isText() {
+		return fmt.Sprintf("%s: %q", path, n.text)
+	}
+	return path
+}
+
+// === XML ==================================================
+
+// parseCanonicalXML parses the XML document into its canonical form:
+// namespace prefixes are replaced by `ns0`, `ns1`, ... in order of first use
+// and declared on the root element, attributes are sorted, comments,
+// processing instructions and whitespace only text are dropped and the
+// remaining text is trimmed.
+func parseCanonicalXML(data []byte) (*markupNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	prefixes := map[string]string{}
+	var namespaces []markupAttr
+
+	qualify := func(n xml.Name) string {
+		switch n.Space {
+		case "":
+			return n.Local
+		case xmlNamespaceURL, "xml":
+			return "xml:" + n.Local
+		}
+
+		prefix, ok := prefixes[n.Space]
+		if !ok {
+			prefix = fmt.Sprintf("ns%d", len(prefixes))
+			prefixes[n.Space] = prefix
+			namespaces = append(namespaces, markupAttr{name: "xmlns:" + prefix, value: n.Space})
+		}
+		return prefix + ":" + n.Local
+	}
+
+	doc := &markupNode{}
+	stack := []*markupNode{doc}
+	var text strings.Builder
+	flushText := func() {
+		if s := strings.TrimSpace(text.String()); s != "" {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, &markupNode{text: s})
+		}
+		text.Reset()
+	}
+
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			flushText()
+			el := &markupNode{name: qualify(tok.Name)}
+			for _, attr := range tok.Attr {
+				if attr.Name.Space == "xmlns" || (attr.Name.Space == "" && attr.Name.Local == "xmlns") {
+					continue
+				}
+				el.attrs = append(el.attrs, markupAttr{name: qualify(attr.Name), value: attr.Value})
+			}
+			sortMarkupAttrs(el.attrs)
+
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, el)
+			stack = append(stack, el)
+
+		case xml.EndElement:
+			flushText()
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if len(stack) > 1 {
+				text.Write(tok)
+			}
+		}
+	}
+
+	var root *markupNode
+	for _, child := range doc.children {
+		if !child.isText() {
+			root = child
+		}
+	}
+	if root == nil {
+		return nil, errors.New("XML document has no root element")
+	}
+	root.attrs = append(namespaces, root.attrs...)
+
+	return doc, nil
+}
+
+// renderCanonicalXML serializes the canonical XML document, indented with two
+// spaces.
+func renderCanonicalXML(doc *markupNode) []byte {
+	var buf bytes.Buffer
+	for _, child := range doc.children {
+		writeCanonicalXML(&buf, child, 0)
+	}
+	return buf.Bytes()
+}
+
+// writeCanonicalXML serializes a node of the canonical XML document.
+func writeCanonicalXML(buf *bytes.Buffer, n *markupNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	if n.isText() {
+		buf.WriteString(indent)
+		_ = xml.EscapeText(buf, []byte(n.text))
+		buf.WriteByte('\n')
+		return
+	}
+
+	buf.WriteString(indent + "<" + n.name)
+	for _, attr := range n.attrs {
+		buf.WriteString(" " + attr.name + `="`)
+		_ = xml.EscapeText(buf, []byte(attr.value))
+		buf.WriteByte('"')
+	}
+	buf.WriteByte('>')
+
+	switch {
+	case len(n.children) == 0:
+	case len(n.children) == 1 && n.children[0].isText():
+		_ = xml.EscapeText(buf, []byte(n.children[0].text))
+	default:
+		buf.WriteByte('\n')
+		for _, child := range n.children {
+			writeCanonicalXML(buf, child, depth+1)
+		}
+		buf.WriteString(indent)
+	}
+	buf.WriteString("</" + n.name + ">\n")
+}
+
+// sortMarkupAttrs sorts the attributes by name.
+func sortMarkupAttrs(attrs []markupAttr) {
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].name < attrs[j].name
+	})
+}
+
+// === HTML =================================================
+
+var (
+	// htmlVoidElements are the elements without end tag.
+	htmlVoidElements = map[string]bool{
+		"area": true, "base": true, "br": true, "col": true, "embed": true,
+		"hr": true, "img": true, "input": true, "link": true, "meta": true,
+		"param": true, "source": true, "track": true, "wbr": true,
+	}
+
+	// htmlInlineElements are the elements whose whitespace is significant.
+	htmlInlineElements = map[string]bool{"pre": true, "textarea": true}
+
+	// htmlRawElements are the elements whose text is not escaped.
+	htmlRawElements = map[string]bool{"script": true, "style": true}
+)
+
+// parseNormalizedHTML parses the HTML document into its normalized form:
+// attributes and class names are sorted, comments are dropped and whitespace
+// is collapsed outside of <pre> and <textarea>.
+func parseNormalizedHTML(data []byte) (*markupNode, error) {
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &markupNode{}
+	for c := root.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.DoctypeNode {
+			doc.attrs = []markupAttr{{name: "doctype", value: c.Data}}
+		}
+	}
+	appendHTMLChildren(doc, root, false)
+	return doc, nil
+}
+
+// appendHTMLChildren converts the children of the HTML node.
+func appendHTMLChildren(parent *markupNode, n *html.Node, preserve bool) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		switch c.Type {
+		case html.ElementNode:
+			el := &markupNode{
+				name:   c.Data,
+				inline: htmlInlineElements[c.Data],
+				raw:    htmlRawElements[c.Data],
+			}
+			for _, attr := range c.Attr {
+				key := attr.Key
+				if attr.Namespace != "" {
+					key = attr.Namespace + ":" + key
+				}
+				value := attr.Val
+				if key == "class" {
+					classes := strings.Fields(value)
+					sort.Strings(classes)
+					value = strings.Join(classes, " ")
+				}
+				el.attrs = append(el.attrs, markupAttr{name: key, value: value})
+			}
+			sortMarkupAttrs(el.attrs)
+
+			appendHTMLChildren(el, c, preserve || el.inline)
+			parent.children = append(parent.children, el)
+
+		case html.TextNode:
+			text := c.Data
+			switch {
+			case preserve:
+			case parent.raw:
+				text = strings.TrimSpace(text)
+			case strings.TrimSpace(text) == "":
+				text = ""
+			default:
+				text = collapseWhitespace(text)
+			}
+			if text != "" {
+				parent.children = append(parent.children, &markupNode{text: text})
+			}
+		}
+	}
+
+	if preserve || len(parent.children) == 0 {
+		return
+	}
+	if first := parent.children[0]; first.isText() {
+		first.text = strings.TrimLeft(first.text, " ")
+	}
+	if last := parent.children[len(parent.children)-1]; last.isText() {
+		last.text = strings.TrimRight(last.text, " ")
+	}
+}
+
+// collapseWhitespace replaces every run of white space by a single space, like
+// a browser renders text outside of <pre>.
+func collapseWhitespace(text string) string {
+	collapsed := strings.Join(strings.Fields(text), " ")
+	if strings.TrimLeft(text, " \t\r\n\f") != text {
+		collapsed = " " + collapsed
+	}
+	if strings.TrimRight(text, " \t\r\n\f") != text {
+		collapsed += " "
+	}
+	return collapsed
+}
+
+// hasText reports whether the element contains text directly, in which case
+// it's rendered on a single line to keep the spaces around its children.
+func (n *markupNode) hasText() bool {
+	for _, child := range n.children {
+		if child.isText() {
+			return true
+		}
+	}
+	return false
+}
+
+// renderNormalizedHTML serializes the normalized HTML document, indented with
+// two spaces.
+func renderNormalizedHTML(doc *markupNode) []byte {
+	var buf bytes.Buffer
+	for _, attr := range doc.attrs {
+		if attr.name == "doctype" {
+			buf.WriteString("<!DOCTYPE " + attr.value + ">\n")
+		}
+	}
+	for _, child := range doc.children {
+		writeNormalizedHTML(&buf, child, 0, false)
+	}
+	return buf.Bytes()
+}
+
+// writeNormalizedHTML serializes a node of the normalized HTML document. Inline
+// nodes are written without indentation and line breaks.
+func writeNormalizedHTML(buf *bytes.Buffer, n *markupNode, depth int, inline bool) {
+	indent, newline := strings.Repeat("  ", depth), "\n"
+	if inline {
+		indent, newline = "", ""
+	}
+
+	if n.isText() {
+		buf.WriteString(indent + html.EscapeString(n.text) + newline)
+		return
+	}
+
+	buf.WriteString(indent + "<" + n.name)
+	for _, attr := range n.attrs {
+		buf.WriteString(" " + attr.name)
+		if attr.value != "" {
+			buf.WriteString(`="` + html.EscapeString(attr.value) + `"`)
+		}
+	}
+	buf.WriteByte('>')
+	if htmlVoidElements[n.name] {
+		buf.WriteString(newline)
+		return
+	}
+
+	switch {
+	case len(n.children) == 0:
+	case n.raw:
+		for _, child := range n.children {
+			buf.WriteString(child.text)
+		}
+	case inline || n.inline || n.hasText():
+		if n.inline && n.children[0].isText() && strings.HasPrefix(n.children[0].text, "\n") {
+			// the parser drops the first line break of <pre> and <textarea>
+			buf.WriteByte('\n')
+		}
+		for _, child := range n.children {
+			writeNormalizedHTML(buf, child, 0, true)
+		}
+	default:
+		buf.WriteByte('\n')
+		for _, child := range n.children {
+			writeNormalizedHTML(buf, child, depth+1, false)
+		}
+		buf.WriteString(indent)
+	}
+	buf.WriteString("</" + n.name + ">" + newline)
+}
@@ -0,0 +1,164 @@
+package golden
+
+import (
+	"encoding/xml"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func canonicalXML(t *testing.T, data string) string {
+	t.Helper()
+	doc, err := parseCanonicalXML([]byte(data))
+	require.NoError(t, err)
+	return string(renderCanonicalXML(doc))
+}
+
+func normalizedHTML(t *testing.T, data string) string {
+	t.Helper()
+	doc, err := parseNormalizedHTML([]byte(data))
+	require.NoError(t, err)
+	return string(renderNormalizedHTML(doc))
+}
+
+func TestCanonicalXML(t *testing.T) {
+	expected := "" +
+		`<ns0:catalog xmlns:ns0="urn:books" xmlns:ns1="urn:meta" lang="en" xml:space="preserve">` + "\n" +
+		`  <ns0:book id="1" ns1:rev="2">` + "\n" +
+		`    <ns0:title>Go &amp; more</ns0:title>` + "\n" +
+		`    <ns0:note></ns0:note>` + "\n" +
+		`  </ns0:book>` + "\n" +
+		`</ns0:catalog>` + "\n"
+
+	assert.Equal(t, expected, canonicalXML(t, `<?xml version="1.0"?>
+<!-- comment -->
+<c:catalog xmlns:c="urn:books" xmlns:m="urn:meta" xml:space="preserve" lang="en">
+  <c:book m:rev="2" id="1"><c:title>  Go &amp; more </c:title><c:note/></c:book>
+</c:catalog>`))
+
+	// other prefixes, a default namespace and CDATA give the same document
+	assert.Equal(t, expected, canonicalXML(t, ``+
+		`<catalog xmlns="urn:books" lang="en" xml:space="preserve">`+
+		`<book id="1" xmlns:x="urn:meta" x:rev="2"><title><![CDATA[Go & more]]></title><note>  </note></book>`+
+		`</catalog>`))
+
+	_, err := parseCanonicalXML([]byte(`<a><b></a>`))
+	assert.Error(t, err)
+
+	_, err = parseCanonicalXML([]byte(`<!-- empty -->`))
+	assert.EqualError(t, err, "XML document has no root element")
+}
+
+func TestNormalizedHTML(t *testing.T) {
+	expected := "" +
+		"<!DOCTYPE html>\n" +
+		"<html>\n" +
+		"  <head>\n" +
+		"    <title>Page</title>\n" +
+		"    <script>if (a < b) {}</script>\n" +
+		"  </head>\n" +
+		"  <body>\n" +
+		`    <div class="a b" id="main">` + "\n" +
+		"      <p>Hello <b>big</b> world</p>\n" +
+		"      <br>\n" +
+		"      <input disabled>\n" +
+		"      <pre>\n\n  keep   this\n</pre>\n" +
+		"    </div>\n" +
+		"  </body>\n" +
+		"</html>\n"
+
+	actual := normalizedHTML(t, `<!doctype html><html><head><title>Page</title>
+<script>  if (a < b) {}  </script></head>
+<body><!-- nav -->
+<div id="main" class=" b  a">
+  <p>Hello
+     <b>big</b>   world</p><br/><input disabled>
+<pre>
+
+  keep   this
+</pre></div></body></html>`)
+	assert.Equal(t, expected, actual)
+
+	// the normalized form is stable
+	assert.Equal(t, expected, normalizedHTML(t, expected))
+
+	// fragments are wrapped like a browser does
+	assert.Equal(t, "<html>\n  <head></head>\n  <body>\n    <p>x</p>\n  </body>\n</html>\n",
+		normalizedHTML(t, "<p>x</p>"))
+}
+
+func TestDiffMarkup(t *testing.T) {
+	expected, err := parseNormalizedHTML([]byte(`<div class="a">one</div><div class="b">two</div><p>x</p>`))
+	require.NoError(t, err)
+	actual, err := parseNormalizedHTML([]byte(`<div class="a">one</div><div class="c" id="x">two!</div><ul></ul>`))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		`~ /html/body/div[2]/@class: "b" => "c"`,
+		`+ /html/body/div[2]/@id: "x"`,
+		`~ /html/body/div[2]/text(): "two" => "two!"`,
+		`- /html/body/p`,
+		`+ /html/body/ul`,
+	}, diffMarkup("", expected, actual))
+}
+
+type book struct {
+	XMLName xml.Name `xml:"book"`
+	ID      string   `xml:"id,attr"`
+	Title   string   `xml:"title"`
+}
+
+func TestAssertXMLCanonical(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithCanonicalXML(true))
+
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "book"), []byte(`<?xml version="1.0"?>
+<book   id="1">
+  <!-- the title -->
+  <title>Go</title>
+</book>`), 0644))
+	g.AssertXML(t, "book", book{ID: "1", Title: "Go"})
+
+	actual, err := parseCanonicalXML([]byte(`<book id="2"><title>Go</title></book>`))
+	require.NoError(t, err)
+	err = g.compareMarkup(t, "book", actual, xmlMarkup)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), `~ /book/@id: "1" => "2"`)
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertXML(t, "updated", book{ID: "1", Title: "Go"})
+	*update = savedUpdateState
+
+	data, err := os.ReadFile(g.GoldenFileName(t, "updated"))
+	require.NoError(t, err)
+	assert.Equal(t, "<book id=\"1\">\n  <title>Go</title>\n</book>\n", string(data))
+}
+
+func TestAssertHTML(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertHTML(t, "page", []byte(`<p class="b a">Hello</p>`))
+	*update = savedUpdateState
+
+	g.AssertHTML(t, "page", []byte("<p  class='a b'>\n  Hello\n</p>"))
+
+	actual, err := parseNormalizedHTML([]byte(`<p class="a b">Bye</p>`))
+	require.NoError(t, err)
+	err = g.compareMarkup(t, "page", actual, htmlMarkup)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), `~ /html/body/p/text(): "Hello" => "Bye"`)
+
+	// reordered siblings fall back to a diff of the documents
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "order"), []byte(`<p>1</p><ul></ul>`), 0644))
+	actual, err = parseNormalizedHTML([]byte(`<ul></ul><p>1</p>`))
+	require.NoError(t, err)
+	err = g.compareMarkup(t, "order", actual, htmlMarkup)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), "Diff is below")
+}
@@ -0,0 +1,177 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const (
+	// anyPattern is the pattern of the `any` matcher, any text up to the end
+	// of the line.
+	anyPattern = `.*`
+
+	// rfc3339Pattern is the pattern of the `timeRFC3339` matcher.
+	rfc3339Pattern = `\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:\.\d+)?(?:Z|[+-]\d{2}:\d{2})`
+)
+
+// matcherPlaceholder finds the placeholders the matchers leave in the
+// executed template.
+var matcherPlaceholder = regexp.MustCompile("\x00([0-9]+)\x00")
+
+// templateMatcher is a matcher used in a golden template.
+type templateMatcher struct {
+	pattern string
+	// call is how the matcher is written in the template, shown in diffs.
+	call string
+}
+
+// templateMatchers collects the matchers a golden template uses. The matchers
+// write placeholders into the executed template, which are matched against
+// the actual data by their patterns.
+type templateMatchers struct {
+	leftDelim  string
+	rightDelim string
+	matchers   []templateMatcher
+}
+
+// funcs returns the matcher functions of the templates:
+//
+//	{{ matches "^[0-9a-f-]{36}$" }} text matching the regular expression
+//	{{ any }}                       any text up to the end of the line
+//	{{ timeRFC3339 }}               a RFC 3339 timestamp
+func (m *templateMatchers) funcs() template.FuncMap {
+	return template.FuncMap{
+		"matches": func(pattern string) (string, error) {
+			pattern = trimAnchors(pattern)
+			if _, err := regexp.Compile(pattern); err != nil {
+				return "", err
+			}
+			return m.add(pattern, "matches "+strconv.Quote(pattern)), nil
+		},
+		"any": func() string {
+			return m.add(anyPattern, "any")
+		},
+		"timeRFC3339": func() string {
+			return m.add(rfc3339Pattern, "timeRFC3339")
+		},
+	}
+}
+
+// add registers a matcher and returns its placeholder.
+func (m *templateMatchers) add(pattern string, call string) string {
+	m.matchers = append(m.matchers, templateMatcher{
+		pattern: pattern,
+		call:    m.leftDelim + " " + call + " " + m.rightDelim,
+	})
+	return fmt.Sprintf("\x00%d\x00", len(m.matchers)-1)
+}
+
+// trimAnchors removes the anchors of a pattern, the matchers always match the
+// text between the surrounding literal text.
+func trimAnchors(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, "^")
+	if strings.HasSuffix(pattern, "$") && !strings.HasSuffix(pattern, `\$`) {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+	return pattern
+}
+
+// match reports whether the actual data matches the executed template.
+func (m *templateMatchers) match(actual []byte, expected []byte) (bool, error) {
+	if len(m.matchers) == 0 {
+		return bytes.Equal(actual, expected), nil
+	}
+
+	re, err := m.compile(expected)
+	if err != nil {
+		return false, err
+	}
+	return re.Match(actual), nil
+}
+
+// compile turns the executed template into an anchored regular expression:
+// the literal text is quoted, the placeholders are replaced by the patterns
+// of their matchers.
+func (m *templateMatchers) compile(expected []byte) (*regexp.Regexp, error) {
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	last := 0
+	for _, loc := range matcherPlaceholder.FindAllSubmatchIndex(expected, -1) {
+		index, _ := strconv.Atoi(string(expected[loc[2]:loc[3]]))
+		pattern.WriteString(regexp.QuoteMeta(string(expected[last:loc[0]])))
+		pattern.WriteString("(?:" + m.matchers[index].pattern + ")")
+		last = loc[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(string(expected[last:])))
+	pattern.WriteString("$")
+
+	return regexp.Compile(pattern.String())
+}
+
+// resolveLines replaces the expected lines containing matchers with the
+// actual lines they match, so the line rules can be applied to both sides
+// afterwards. Without unordered, a line is matched against the actual line at
+// the same position; with it, against any actual line that isn't equal to a
+// literal expected line. Lines that match nothing keep their placeholders and
+// fail the comparison.
+func (m *templateMatchers) resolveLines(actual [][]byte, expected [][]byte, unordered bool) ([]byte, error) {
+	resolved := make([][]byte, len(expected))
+	copy(resolved, expected)
+
+	var candidates [][]byte
+	if unordered {
+		literal := map[string]int{}
+		for _, line := range expected {
+			if !matcherPlaceholder.Match(line) {
+				literal[string(line)]++
+			}
+		}
+		for _, line := range actual {
+			if literal[string(line)] > 0 {
+				literal[string(line)]--
+				continue
+			}
+			candidates = append(candidates, line)
+		}
+	}
+
+	used := make([]bool, len(candidates))
+	for i, line := range expected {
+		if !matcherPlaceholder.Match(line) {
+			continue
+		}
+		re, err := m.compile(line)
+		if err != nil {
+			return nil, err
+		}
+
+		if !unordered {
+			if i < len(actual) && re.Match(actual[i]) {
+				resolved[i] = actual[i]
+			}
+			continue
+		}
+		for j, candidate := range candidates {
+			if !used[j] && re.Match(candidate) {
+				resolved[i] = candidate
+				used[j] = true
+				break
+			}
+		}
+	}
+
+	return joinLines(resolved), nil
+}
+
+// display replaces the placeholders in the executed template with the
+// matchers as they are written in the template, for the diff.
+func (m *templateMatchers) display(expected []byte) string {
+	return matcherPlaceholder.ReplaceAllStringFunc(string(expected), func(placeholder string) string {
+		index, _ := strconv.Atoi(strings.Trim(placeholder, "\x00"))
+		return m.matchers[index].call
+	})
+}
@@ -0,0 +1,119 @@
+package golden
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateMatchers(t *testing.T) {
+	tests := map[string]struct {
+		fixture string
+		actual  string
+		err     error
+	}{
+		"matches": {
+			fixture: `id: {{ matches "^[0-9a-f-]{36}$" }}` + "\n",
+			actual:  "id: 3f2c1a9e-8b7d-4c6e-9f1a-2b3c4d5e6f70\n",
+		},
+		"matches mismatch": {
+			fixture: `id: {{ matches "^[0-9]+$" }}` + "\n",
+			actual:  "id: abc\n",
+			err:     &errFixtureMismatch{},
+		},
+		"any": {
+			fixture: "a: {{ any }}\nb: {{ .Name }}\n",
+			actual:  "a: whatever (1)\nb: gopher\n",
+		},
+		"any stops at the line end": {
+			fixture: "a: {{ any }}\n",
+			actual:  "a: x\nb: y\n",
+			err:     &errFixtureMismatch{},
+		},
+		"time": {
+			fixture: "created: {{ timeRFC3339 }}, updated: {{ timeRFC3339 }}\n",
+			actual:  "created: 2023-05-01T10:00:00Z, updated: 2023-05-01T12:00:00.123+02:00\n",
+		},
+		"literal text is not a pattern": {
+			fixture: "a.b {{ any }}\n",
+			actual:  "axb c\n",
+			err:     &errFixtureMismatch{},
+		},
+		"invalid pattern": {
+			fixture: `{{ matches "(" }}`,
+			actual:  "(",
+			err:     &errMissingKey{},
+		},
+	}
+
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+	data := struct{ Name string }{Name: "gopher"}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			file := strings.ReplaceAll(name, " ", "-")
+			require.NoError(t, os.WriteFile(g.GoldenFileName(t, file), []byte(test.fixture), 0644))
+
+			err := g.compareTemplate(t, file, data, []byte(test.actual))
+			assert.IsType(t, test.err, err)
+		})
+	}
+}
+
+func TestTemplateMatchersDiff(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithDiffEngine(Simple))
+
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "diff"), []byte(`id: {{ matches "^[0-9]+$" }}`), 0644))
+	err := g.compareTemplate(t, "diff", nil, []byte("id: x"))
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), `Expected: id: {{ matches "[0-9]+" }}`)
+}
+
+func TestTemplateMatchersWithLineRules(t *testing.T) {
+	dir := t.TempDir()
+	// the placeholders sort before the literal line, the actual lines after it
+	fixture := "{{ timeRFC3339 }} started\n{{ any }} done\nb: literal\n"
+
+	g := New(t, WithFixtureDir(dir), WithUnorderedLines(true))
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "unordered"), []byte(fixture), 0644))
+	assert.NoError(t, g.compareTemplate(t, "unordered", nil, []byte("b: literal\nz done\n2023-05-01T10:00:00Z started\n")))
+
+	err := g.compareTemplate(t, "unordered", nil, []byte("b: literal\nz done\nyesterday started\n"))
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), "{{ timeRFC3339 }}")
+
+	g = New(t, WithFixtureDir(dir), WithIgnoreWhitespace(true), WithIgnoreLines("^#"))
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "whitespace"), []byte("a:   {{ any }}\nb: {{ timeRFC3339 }}\n"), 0644))
+	assert.NoError(t, g.compareTemplate(t, "whitespace", nil, []byte("# comment\n  a: x   y\nb:  2023-05-01T10:00:00Z\n")))
+}
+
+func TestTemplateFuncsAndDelims(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t,
+		WithFixtureDir(dir),
+		WithTemplateDelims("[[", "]]"),
+		WithTemplateFuncs(template.FuncMap{"upper": strings.ToUpper}),
+	)
+
+	require.NoError(t, os.WriteFile(g.GoldenFileName(t, "chart"), []byte(""+
+		"name: {{ .Release.Name }}\n"+
+		"app: [[ upper .Name ]]\n"+
+		"version: [[ any ]]\n"), 0644))
+
+	g.AssertWithTemplate(t, "chart", struct{ Name string }{"gopher"}, []byte(""+
+		"name: {{ .Release.Name }}\n"+
+		"app: GOPHER\n"+
+		"version: 1.2.3\n"))
+
+	assert.EqualError(t, New(t).WithTemplateDelims("", "]]"), "template delimiters must not be empty")
+
+	// the functions of a shared tester are not modified by per-call options
+	g.with(t, []Option{WithTemplateFuncs(template.FuncMap{"lower": strings.ToLower})})
+	assert.Len(t, g.templateFuncs, 1)
+}
@@ -0,0 +1,339 @@
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// metadataMarker is the first line of a golden file with a metadata
+	// header.
+	metadataMarker = "golden-metadata: v1\n"
+
+	// metadataTerminator ends the metadata header.
+	metadataTerminator = "---\n"
+)
+
+// Metadata describes how a golden file was produced. It's written as a header
+// by Update if WithMetadata is enabled and stripped before any comparison.
+type Metadata struct {
+	// Test is the full name of the test that produced the golden file.
+	Test string
+	// Package is the import path of the package of the test.
+	Package string
+	// GoVersion is the version of the Go release that ran the test.
+	GoVersion string
+	// Updated is the time the content of the golden file last changed.
+	Updated time.Time
+	// ContentType is the MIME type of the content.
+	ContentType string
+	// Scrubbers lists the rules that removed or masked volatile values before
+	// the comparison, e.g. `ignore-line:^took`.
+	Scrubbers []string
+}
+
+// LintIssue is a problem found by LintFixtures.
+type LintIssue struct {
+	// File is the golden file the issue was found in.
+	File string
+	// Message describes the issue.
+	Message string
+}
+
+// String returns the issue in a `file: message` form.
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s: %s", i.File, i.Message)
+}
+
+// header returns the metadata header.
+func (m *Metadata) header() []byte {
+	var buf bytes.Buffer
+	buf.WriteString(metadataMarker)
+	fmt.Fprintf(&buf, "test: %s\n", m.Test)
+	fmt.Fprintf(&buf, "package: %s\n", m.Package)
+	fmt.Fprintf(&buf, "go: %s\n", m.GoVersion)
+	fmt.Fprintf(&buf, "updated: %s\n", m.Updated.UTC().Format(time.RFC3339))
+	fmt.Fprintf(&buf, "content-type: %s\n", m.ContentType)
+	for _, scrubber := range m.Scrubbers {
+		fmt.Fprintf(&buf, "scrubber: %s\n", scrubber)
+	}
+	buf.WriteString(metadataTerminator)
+	return buf.Bytes()
+}
+
+// splitMetadata separates the metadata header from the content of a golden
+// file. The metadata is nil if the file has no header.
+func splitMetadata(data []byte) (*Metadata, []byte, error) {
+	rest, ok := cutMetadataMarker(data)
+	if !ok {
+		return nil, data, nil
+	}
+
+	m := &Metadata{}
+	for len(rest) > 0 {
+		var line string
+		line, rest = nextMetadataLine(rest)
+		if line+"\n" == metadataTerminator {
+			return m, rest, nil
+		}
+
+		field := strings.SplitN(line, ": ", 2)
+		if len(field) != 2 {
+			return nil, nil, fmt.Errorf("invalid golden metadata line %q", line)
+		}
+
+		switch field[0] {
+		case "test":
+			m.Test = field[1]
+		case "package":
+			m.Package = field[1]
+		case "go":
+			m.GoVersion = field[1]
+		case "updated":
+			updated, err := time.Parse(time.RFC3339, field[1])
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid golden metadata timestamp: %w", err)
+			}
+			m.Updated = updated
+		case "content-type":
+			m.ContentType = field[1]
+		case "scrubber":
+			m.Scrubbers = append(m.Scrubbers, field[1])
+		}
+	}
+
+	return nil, nil, fmt.Errorf("golden metadata header is not terminated by %q", strings.TrimSpace(metadataTerminator))
+}
+
+// cutMetadataMarker returns the data following the metadata marker, which may
+// end with CRLF if the file was checked out on Windows.
+func cutMetadataMarker(data []byte) ([]byte, bool) {
+	line, rest := nextMetadataLine(data)
+	if line+"\n" != metadataMarker {
+		return data, false
+	}
+	return rest, true
+}
+
+// nextMetadataLine returns the first line of data without its line ending and
+// the data following it.
+func nextMetadataLine(data []byte) (string, []byte) {
+	line, rest := data, data[len(data):]
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		line, rest = data[:i], data[i+1:]
+	}
+	return string(bytes.TrimSuffix(line, []byte("\r"))), rest
+}
+
+// ReadMetadata returns the metadata header of the golden file. The metadata is
+// nil if the file has no header.
+func ReadMetadata(goldenFile string) (*Metadata, error) {
+	data, err := os.ReadFile(goldenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = decompress(compressionFor(goldenFile), data)
+	if err != nil {
+		return nil, err
+	}
+
+	m, _, err := splitMetadata(data)
+	return m, err
+}
+
+// Metadata returns the metadata header of the golden fixture used by the
+// test. The metadata is nil if the fixture has no header.
+func (g *Golden) Metadata(t *testing.T, name string) (*Metadata, error) {
+	return ReadMetadata(g.resolveFixture(t, name, g.GoldenFileName))
+}
+
+// withMetadata prepends the metadata header to the data that is written to the
+// golden file. If the content of the golden file does not change, the
+// timestamp of the existing header is kept, so updating does not touch
+// unchanged fixtures.
+func (g *Golden) withMetadata(t *testing.T, goldenFile string, data []byte) ([]byte, error) {
+	existing, err := readFixture(goldenFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	m := g.newMetadata(t, goldenFile, data, err == nil && bytes.Equal(existing, data))
+	return append(m.header(), data...), nil
+}
+
+// newMetadata returns the metadata of a golden file that is being updated.
+// The content type is detected from the leading bytes of the data unless it
+// is known. If the content is unchanged, the timestamp of the existing header
+// is kept.
+func (g *Golden) newMetadata(t *testing.T, goldenFile string, leading []byte, unchanged bool) *Metadata {
+	m := &Metadata{
+		Test:        t.Name(),
+		Package:     testPackage(t),
+		GoVersion:   runtime.Version(),
+		Updated:     ts.UTC().Truncate(time.Second),
+		ContentType: g.contentType,
+		Scrubbers:   g.scrubbers(),
+	}
+	if m.ContentType == "" {
+		m.ContentType = http.DetectContentType(leading)
+	}
+
+	if unchanged {
+		if previous, err := ReadMetadata(goldenFile); err == nil && previous != nil {
+			m.Updated = previous.Updated
+		}
+	}
+
+	return m
+}
+
+// scrubbers lists the rules that remove or mask volatile values.
+func (g *Golden) scrubbers() []string {
+	var scrubbers []string
+	for _, re := range g.ignoreLines {
+		scrubbers = append(scrubbers, "ignore-line:"+re.String())
+	}
+	for _, column := range g.maskedColumns {
+		scrubbers = append(scrubbers, "mask-column:"+column)
+	}
+	return scrubbers
+}
+
+// withContentType returns a copy of the tester that records the content type
+// in the metadata header.
+func (g *Golden) withContentType(contentType string) *Golden {
+	c := *g
+	c.contentType = contentType
+	return &c
+}
+
+// testPackage returns the import path of the package of the test by looking
+// for the test function on the call stack.
+func testPackage(t *testing.T) string {
+	test := strings.Split(t.Name(), "/")[0]
+
+	pc := make([]uintptr, 64)
+	frames := runtime.CallersFrames(pc[:runtime.Callers(2, pc)])
+	for {
+		frame, more := frames.Next()
+		if pkg, ok := functionPackage(frame.Function, test); ok {
+			return pkg
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// functionPackage returns the package of the fully qualified function if it is
+// the test function or one of its closures. The package path may contain dots
+// itself (gopkg.in/yaml.v3), so it ends right before the test symbol.
+func functionPackage(fn string, test string) (string, bool) {
+	if strings.HasSuffix(fn, "."+test) {
+		return strings.TrimSuffix(fn, "."+test), true
+	}
+	if i := strings.LastIndex(fn, "."+test+"."); i >= 0 {
+		return fn[:i], true
+	}
+	return "", false
+}
+
+// LintFixtures checks the metadata headers of the golden files in fixtureDir
+// and reports the fixtures produced by a test that no longer exists in the
+// test files of packageDir. Golden files without header are ignored.
+func LintFixtures(fixtureDir string, packageDir string) ([]LintIssue, error) {
+	tests, err := testFunctions(packageDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []LintIssue
+	err = filepath.Walk(fixtureDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		m, err := ReadMetadata(path)
+		if err != nil {
+			issues = append(issues, LintIssue{File: path, Message: err.Error()})
+			return nil
+		}
+		if m == nil {
+			return nil
+		}
+
+		test := strings.Split(m.Test, "/")[0]
+		if !tests[test] {
+			issues = append(issues, LintIssue{
+				File:    path,
+				Message: fmt.Sprintf("produced by %s which no longer exists", test),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return issues[i].File < issues[j].File
+	})
+	return issues, nil
+}
+
+// testFunctions returns the names of the top level test functions declared in
+// the test files of the package directory.
+func testFunctions(packageDir string) (map[string]bool, error) {
+	files, err := filepath.Glob(filepath.Join(packageDir, "*_test.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	fset := token.NewFileSet()
+	tests := map[string]bool{}
+	for _, name := range files {
+		file, err := parser.ParseFile(fset, name, nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, err
+		}
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && strings.HasPrefix(fn.Name.Name, "Test") {
+				tests[fn.Name.Name] = true
+			}
+		}
+	}
+	return tests, nil
+}
+
+// skipMetadata consumes the metadata header, if any, of a golden file that is
+// being streamed.
+func skipMetadata(r *bufio.Reader) error {
+	// a short read leaves fewer bytes than asked for, which is not a header
+	peeked, _ := r.Peek(len(metadataMarker) + 1)
+	if _, ok := cutMetadataMarker(peeked); !ok {
+		return nil
+	}
+
+	for {
+		raw, err := r.ReadString('\n')
+		if line, _ := nextMetadataLine([]byte(raw)); line+"\n" == metadataTerminator && strings.HasSuffix(raw, "\n") {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("golden metadata header is not terminated by %q", strings.TrimSpace(metadataTerminator))
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitMetadata(t *testing.T) {
+	m := &Metadata{
+		Test:        "TestExample/sub",
+		Package:     "github.com/nao1215/gorky/golden",
+		GoVersion:   "go1.19.5",
+		Updated:     time.Date(2023, 6, 3, 1, 2, 3, 0, time.UTC),
+		ContentType: "application/json",
+		Scrubbers:   []string{"ignore-line:^took", "mask-column:id"},
+	}
+
+	parsed, body, err := splitMetadata(append(m.header(), "content\n---\n"...))
+	require.NoError(t, err)
+	assert.Equal(t, m, parsed)
+	assert.Equal(t, "content\n---\n", string(body))
+
+	parsed, body, err = splitMetadata([]byte("no header"))
+	require.NoError(t, err)
+	assert.Nil(t, parsed)
+	assert.Equal(t, "no header", string(body))
+
+	crlf := strings.ReplaceAll(string(m.header()), "\n", "\r\n")
+	parsed, body, err = splitMetadata([]byte(crlf + "content\r\n"))
+	require.NoError(t, err)
+	assert.Equal(t, m, parsed)
+	assert.Equal(t, "content\r\n", string(body))
+
+	_, _, err = splitMetadata([]byte(metadataMarker + "test: TestExample\n"))
+	assert.Error(t, err)
+
+	_, _, err = splitMetadata([]byte(metadataMarker + "updated: yesterday\n---\n"))
+	assert.Error(t, err)
+}
+
+func TestTestPackage(t *testing.T) {
+	assert.Equal(t, "github.com/nao1215/gorky/golden", testPackage(t))
+
+	t.Run("sub test", func(t *testing.T) {
+		assert.Equal(t, "github.com/nao1215/gorky/golden", testPackage(t))
+	})
+
+	tests := []struct {
+		fn  string
+		pkg string
+		ok  bool
+	}{
+		{fn: "gopkg.in/yaml.v3.TestX", pkg: "gopkg.in/yaml.v3", ok: true},
+		{fn: "gopkg.in/yaml.v3.TestX.func1", pkg: "gopkg.in/yaml.v3", ok: true},
+		{fn: "example.com/pkg.TestX.func1.2", pkg: "example.com/pkg", ok: true},
+		{fn: "example.com/pkg.TestXY"},
+		{fn: "testing.tRunner"},
+	}
+	for _, tt := range tests {
+		pkg, ok := functionPackage(tt.fn, "TestX")
+		assert.Equal(t, tt.ok, ok, tt.fn)
+		assert.Equal(t, tt.pkg, pkg, tt.fn)
+	}
+}
+
+func TestUpdateWithMetadata(t *testing.T) {
+	g := New(t, WithMetadata(true), WithIgnoreLines(`^took`), WithCompression(Gzip))
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	savedTs := ts
+	defer func() { ts = savedTs }()
+	ts = time.Date(2023, 6, 3, 0, 0, 0, 0, time.UTC)
+
+	require.NoError(t, g.withContentType("application/json").Update(t, "meta", []byte(`{"a": 1}`)))
+	m, err := g.Metadata(t, "meta")
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, "TestUpdateWithMetadata", m.Test)
+	assert.Equal(t, "github.com/nao1215/gorky/golden", m.Package)
+	assert.Equal(t, ts, m.Updated)
+	assert.Equal(t, "application/json", m.ContentType)
+	assert.Equal(t, []string{"ignore-line:^took"}, m.Scrubbers)
+
+	// the header is not part of the comparison
+	assert.Nil(t, g.compare(t, "meta", []byte(`{"a": 1}`)))
+	assert.Nil(t, New(t, WithCompression(Gzip)).compare(t, "meta", []byte(`{"a": 1}`)))
+
+	// the timestamp only changes with the content
+	ts = ts.Add(time.Hour)
+	require.NoError(t, g.Update(t, "meta", []byte(`{"a": 1}`)))
+	m, err = g.Metadata(t, "meta")
+	require.NoError(t, err)
+	assert.Equal(t, ts.Add(-time.Hour), m.Updated)
+
+	require.NoError(t, g.Update(t, "meta", []byte(`{"a": 2}`)))
+	m, err = g.Metadata(t, "meta")
+	require.NoError(t, err)
+	assert.Equal(t, ts, m.Updated)
+	assert.Equal(t, "text/plain; charset=utf-8", m.ContentType)
+}
+
+func TestLintFixtures(t *testing.T) {
+	dir := t.TempDir()
+	fixtures := filepath.Join(dir, "testdata")
+	require.NoError(t, os.MkdirAll(fixtures, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "example_test.go"), []byte(`package example
+
+import "testing"
+
+func TestAlive(t *testing.T) {}
+`), 0600))
+
+	write := func(name string, m *Metadata) {
+		var data []byte
+		if m != nil {
+			data = m.header()
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(fixtures, name), append(data, "data"...), 0600))
+	}
+	write("alive.golden", &Metadata{Test: "TestAlive/sub"})
+	write("removed.golden", &Metadata{Test: "TestRemoved"})
+	write("plain.golden", nil)
+	require.NoError(t, os.WriteFile(filepath.Join(fixtures, "broken.golden"), []byte(metadataMarker), 0600))
+
+	issues, err := LintFixtures(fixtures, dir)
+	require.NoError(t, err)
+	require.Len(t, issues, 2)
+	assert.Equal(t, filepath.Join(fixtures, "broken.golden"), issues[0].File)
+	assert.Equal(t, filepath.Join(fixtures, "removed.golden")+": produced by TestRemoved which no longer exists", issues[1].String())
+}
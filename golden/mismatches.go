@@ -0,0 +1,138 @@
+package golden
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	// KnownMismatchesFile is the name of the file, stored next to the golden
+	// files, that lists the golden files which are known to mismatch.
+	KnownMismatchesFile = "known-mismatches.txt"
+
+	// knownMismatchDateLayout is the layout of the expiry dates.
+	knownMismatchDateLayout = "2006-01-02"
+)
+
+// KnownMismatch is an entry of the known mismatches file. A mismatch of the
+// golden file is only logged as a warning until the end of the expiry date.
+type KnownMismatch struct {
+	// Name is the name of the golden file, as passed to the assertions.
+	Name string
+	// Expires is the last day the mismatch is accepted.
+	Expires time.Time
+	// Reason explains why the golden file is allowed to mismatch.
+	Reason string
+}
+
+// expired reports whether the entry is no longer accepted at the given time.
+func (k KnownMismatch) expired(now time.Time) bool {
+	return !now.Before(k.Expires.AddDate(0, 0, 1))
+}
+
+// ReadKnownMismatches parses a known mismatches file. Every non empty line
+// that does not start with `#` holds the name of the golden file, the expiry
+// date as `YYYY-MM-DD` and the reason, separated by white space:
+//
+//	# name      expires     reason
+//	user-list   2024-03-31  upstream sorts by id, fixed with #123
+//
+// A missing file is not an error and returns no entries.
+func ReadKnownMismatches(path string) ([]KnownMismatch, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []KnownMismatch
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("%s:%d: expected a name, an expiry date and a reason", path, n)
+		}
+
+		expires, err := time.ParseInLocation(knownMismatchDateLayout, fields[1], time.Local)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid expiry date %q", path, n, fields[1])
+		}
+
+		reason := strings.TrimSpace(line[len(fields[0]):])
+		reason = strings.TrimSpace(reason[len(fields[1]):])
+		entries = append(entries, KnownMismatch{Name: fields[0], Expires: expires, Reason: reason})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// knownMismatch returns the entry of the known mismatches file next to the
+// golden file, or nil if the golden file is not listed.
+func (g *Golden) knownMismatch(t *testing.T, name string) (*KnownMismatch, error) {
+	path := filepath.Join(filepath.Dir(g.GoldenFileName(t, name)), KnownMismatchesFile)
+	entries, err := ReadKnownMismatches(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		if entries[i].Name == name {
+			return &entries[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// acceptKnownMismatch turns the mismatch of a golden file listed in the known
+// mismatches file into a warning, as long as the entry has not expired. If a
+// listed golden file matches again, it's logged so the entry can be removed.
+func (g *Golden) acceptKnownMismatch(t *testing.T, name string, err error) error {
+	t.Helper()
+
+	var mismatch *errFixtureMismatch
+	if err != nil && !errors.As(err, &mismatch) {
+		return err
+	}
+
+	known, knownErr := g.knownMismatch(t, name)
+	if knownErr != nil {
+		return knownErr
+	}
+	if known == nil {
+		return err
+	}
+
+	expires := known.Expires.Format(knownMismatchDateLayout)
+	switch {
+	case err == nil:
+		t.Logf("golden file %q matches again, remove it from %s", name, KnownMismatchesFile)
+		return nil
+
+	case known.expired(ts):
+		return newErrFixtureMismatch(fmt.Sprintf(
+			"known mismatch of golden file %q expired on %s (%s)\n\n%s",
+			name, expires, known.Reason, mismatch.Error(),
+		))
+	}
+
+	t.Logf("WARNING: golden file %q is a known mismatch until %s (%s)\n\n%s",
+		name, expires, known.Reason, mismatch.Error())
+	return nil
+}
@@ -0,0 +1,100 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKnownMismatches(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, KnownMismatchesFile)
+
+	entries, err := ReadKnownMismatches(path)
+	assert.NoError(t, err)
+	assert.Nil(t, entries)
+
+	require.NoError(t, os.WriteFile(path, []byte(""+
+		"# name   expires     reason\n"+
+		"\n"+
+		"user-list  2024-03-31  upstream sorts  by id\n"), 0644))
+
+	entries, err = ReadKnownMismatches(path)
+	require.NoError(t, err)
+	assert.Equal(t, []KnownMismatch{{
+		Name:    "user-list",
+		Expires: time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local),
+		Reason:  "upstream sorts  by id",
+	}}, entries)
+
+	require.NoError(t, os.WriteFile(path, []byte("user-list 2024-03-31\n"), 0644))
+	_, err = ReadKnownMismatches(path)
+	assert.EqualError(t, err, path+":1: expected a name, an expiry date and a reason")
+
+	require.NoError(t, os.WriteFile(path, []byte("user-list 31.03.2024 reason\n"), 0644))
+	_, err = ReadKnownMismatches(path)
+	assert.EqualError(t, err, path+`:1: invalid expiry date "31.03.2024"`)
+}
+
+func TestKnownMismatchExpired(t *testing.T) {
+	k := KnownMismatch{Expires: time.Date(2024, 3, 31, 0, 0, 0, 0, time.Local)}
+	assert.False(t, k.expired(time.Date(2024, 3, 31, 23, 59, 0, 0, time.Local)))
+	assert.True(t, k.expired(time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local)))
+}
+
+func TestAcceptKnownMismatch(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+	require.NoError(t, g.Update(t, "allowed", []byte("expected")))
+	require.NoError(t, g.Update(t, "expired", []byte("expected")))
+	require.NoError(t, g.Update(t, "unlisted", []byte("expected")))
+
+	tomorrow := ts.AddDate(0, 0, 1).Format(knownMismatchDateLayout)
+	yesterday := ts.AddDate(0, 0, -1).Format(knownMismatchDateLayout)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, KnownMismatchesFile), []byte(""+
+		"allowed "+tomorrow+" staged upstream fix\n"+
+		"expired "+yesterday+" should have been fixed\n"), 0644))
+
+	// a listed mismatch is accepted until the expiry date
+	assert.NoError(t, g.acceptKnownMismatch(t, "allowed", g.compare(t, "allowed", []byte("actual"))))
+
+	// a listed golden file that matches again passes
+	assert.NoError(t, g.acceptKnownMismatch(t, "allowed", g.compare(t, "allowed", []byte("expected"))))
+
+	// an expired entry fails again
+	err := g.acceptKnownMismatch(t, "expired", g.compare(t, "expired", []byte("actual")))
+	assert.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), `known mismatch of golden file "expired" expired on `+yesterday+" (should have been fixed)")
+
+	// other golden files are not affected
+	assert.IsType(t, &errFixtureMismatch{},
+		g.acceptKnownMismatch(t, "unlisted", g.compare(t, "unlisted", []byte("actual"))))
+
+	// only mismatches are accepted
+	assert.IsType(t, &errFixtureNotFound{},
+		g.acceptKnownMismatch(t, "missing", g.compare(t, "missing", []byte("actual"))))
+}
+
+func TestCleanKeepsKnownMismatches(t *testing.T) {
+	savedCleanState := *clean
+	*clean = true
+	defer func() { *clean = savedCleanState }()
+
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+	content := []byte("allowed 2024-03-31 staged upstream fix\n")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "old.golden"), []byte("old"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, KnownMismatchesFile), content, 0644))
+	require.NoError(t, os.Chtimes(dir, ts.Add(-time.Hour), ts.Add(-time.Hour)))
+
+	require.NoError(t, g.Update(t, "new", []byte("new")))
+
+	assert.NoFileExists(t, filepath.Join(dir, "old.golden"))
+	data, err := os.ReadFile(filepath.Join(dir, KnownMismatchesFile))
+	require.NoError(t, err)
+	assert.Equal(t, content, data)
+}
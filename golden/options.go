@@ -1,6 +1,12 @@
 package golden
 
-import "os"
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"text/template"
+)
 
 // WithFixtureDir sets the fixture directory.
 //
@@ -60,6 +66,34 @@ func (g *Golden) WithIgnoreTemplateErrors(ignoreErrors bool) error {
 	return nil
 }
 
+// WithTemplateFuncs adds functions to the templates of AssertWithTemplate.
+// Functions with the name of a matcher replace the matcher.
+func (g *Golden) WithTemplateFuncs(funcs template.FuncMap) error {
+	// the map may be shared with other testers
+	merged := template.FuncMap{}
+	for name, fn := range g.templateFuncs {
+		merged[name] = fn
+	}
+	for name, fn := range funcs {
+		merged[name] = fn
+	}
+	g.templateFuncs = merged
+	return nil
+}
+
+// WithTemplateDelims sets the action delimiters of the templates of
+// AssertWithTemplate.
+//
+// Defaults to `{{` and `}}`.
+func (g *Golden) WithTemplateDelims(left, right string) error {
+	if left == "" || right == "" {
+		return fmt.Errorf("template delimiters must not be empty")
+	}
+	g.templateLeftDelim = left
+	g.templateRightDelim = right
+	return nil
+}
+
 // WithTestNameForDir will create a directory with the test's name in the
 // fixture directory to store all the golden files.
 //
@@ -78,3 +112,211 @@ func (g *Golden) WithSubTestNameForDir(use bool) error {
 	g.useSubTestNameForDir = use
 	return nil
 }
+
+// WithUnorderedLines makes the comparison insensitive to the order of the
+// lines. The actual and expected data are compared as a multiset of lines,
+// which is useful for output produced by concurrent goroutines.
+//
+// Default value is false.
+func (g *Golden) WithUnorderedLines(unordered bool) error {
+	g.unorderedLines = unordered
+	return nil
+}
+
+// WithIgnoreLines removes every line matching one of the regular expressions
+// from both the actual and the expected data before they are compared.
+func (g *Golden) WithIgnoreLines(patterns ...string) error {
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid ignore line pattern %q: %w", pattern, err)
+		}
+		g.ignoreLines = append(g.ignoreLines, re)
+	}
+	return nil
+}
+
+// WithIgnoreWhitespace makes the comparison insensitive to whitespace. Leading
+// and trailing whitespace is trimmed, inner runs of whitespace are collapsed
+// to a single space and blank lines are dropped.
+//
+// Default value is false.
+func (g *Golden) WithIgnoreWhitespace(ignore bool) error {
+	g.ignoreWhitespace = ignore
+	return nil
+}
+
+// WithCompression sets the compression format of the golden files. The
+// matching suffix (`.gz` or `.zst`) is appended to the fixture name.
+//
+// Default value is NoCompression.
+func (g *Golden) WithCompression(compression Compression) error {
+	g.compression = compression
+	return nil
+}
+
+// WithContentHash stores only the SHA-256 digest, the length and a short
+// preview of the output in the golden file instead of the output itself. The
+// digest is taken from the raw output, the encoding and line ending options
+// don't apply.
+//
+// Default value is false.
+func (g *Golden) WithContentHash(use bool) error {
+	g.contentHash = use
+	return nil
+}
+
+// WithArtifactsDir sets the directory the actual output is dumped to when a
+// content hash fixture does not match.
+//
+// Defaults to `golden-artifacts` in os.TempDir().
+func (g *Golden) WithArtifactsDir(dir string) error {
+	g.artifactsDir = dir
+	return nil
+}
+
+// WithProtoFormat sets the format protocol buffer messages are stored in by
+// AssertProto.
+//
+// Default value is ProtoText.
+func (g *Golden) WithProtoFormat(format ProtoFormat) error {
+	g.protoFormat = format
+	return nil
+}
+
+// WithPixelTolerance sets how much each color channel of a pixel may differ
+// before AssertImage considers the pixel as changed.
+//
+// Default value is 0.
+func (g *Golden) WithPixelTolerance(tolerance uint8) error {
+	g.pixelTolerance = tolerance
+	return nil
+}
+
+// WithMaxDiffPixelRatio sets the ratio of changed pixels, between 0 and 1,
+// that AssertImage accepts.
+//
+// Default value is 0.
+func (g *Golden) WithMaxDiffPixelRatio(ratio float64) error {
+	if ratio < 0 || ratio > 1 {
+		return fmt.Errorf("max diff pixel ratio must be between 0 and 1, got %v", ratio)
+	}
+	g.maxDiffPixelRatio = ratio
+	return nil
+}
+
+// WithDBDumpFormat sets the format AssertDB dumps the database state in.
+//
+// Default value is DBDumpText.
+func (g *Golden) WithDBDumpFormat(format DBDumpFormat) error {
+	g.dbDumpFormat = format
+	return nil
+}
+
+// WithMaskedColumns sets the columns whose values are replaced by `<masked>`
+// in the dumps of AssertDB. Column names are matched case-insensitively.
+func (g *Golden) WithMaskedColumns(columns ...string) error {
+	g.maskedColumns = append(g.maskedColumns, columns...)
+	return nil
+}
+
+// WithFixtureVariants sets the variants, ordered from the most to the least
+// specific, used to resolve the fixtures.
+func (g *Golden) WithFixtureVariants(variants ...string) error {
+	for _, variant := range variants {
+		if variant == "" || strings.ContainsAny(variant, `/\`) {
+			return fmt.Errorf("invalid fixture variant %q", variant)
+		}
+	}
+	g.fixtureVariants = append(g.fixtureVariants, variants...)
+	return nil
+}
+
+// WithLineEndings sets how line endings are handled before the data is
+// compared or written to the golden files.
+//
+// Default value is LF.
+func (g *Golden) WithLineEndings(lineEnding LineEnding) error {
+	g.lineEnding = lineEnding
+	return nil
+}
+
+// WithTrailingNewline sets how a line ending at the end of the data is
+// handled.
+//
+// Default value is PreserveTrailingNewline.
+func (g *Golden) WithTrailingNewline(trailingNewline TrailingNewline) error {
+	g.trailingNewline = trailingNewline
+	return nil
+}
+
+// WithStripBOM removes the UTF-8 byte order mark at the start of the data.
+//
+// Default value is false.
+func (g *Golden) WithStripBOM(strip bool) error {
+	g.stripBOM = strip
+	return nil
+}
+
+// WithUTF16Decoding converts UTF-16 encoded data into UTF-8.
+//
+// Default value is false.
+func (g *Golden) WithUTF16Decoding(decode bool) error {
+	g.decodeUTF16 = decode
+	return nil
+}
+
+// WithMetadata writes a metadata header to the golden files on update.
+//
+// Default value is false.
+func (g *Golden) WithMetadata(use bool) error {
+	g.metadata = use
+	return nil
+}
+
+// WithCanonicalXML canonicalizes the documents of AssertXML before they are
+// compared or written.
+//
+// Default value is false.
+func (g *Golden) WithCanonicalXML(canonical bool) error {
+	g.canonicalXML = canonical
+	return nil
+}
+
+// WithGoAST compares the tokens of the sources of AssertGoSource, ignoring
+// comments and layout.
+//
+// Default value is false.
+func (g *Golden) WithGoAST(use bool) error {
+	g.goAST = use
+	return nil
+}
+
+// WithTableFormat sets the format AssertTable renders the rows in.
+//
+// Default value is TableMarkdown.
+func (g *Golden) WithTableFormat(format TableFormat) error {
+	g.tableFormat = format
+	return nil
+}
+
+// WithTableColumns sets the columns AssertTable renders, in their order.
+// Without columns all columns are rendered.
+func (g *Golden) WithTableColumns(columns ...string) error {
+	seen := map[string]bool{}
+	for _, column := range columns {
+		if seen[column] {
+			return fmt.Errorf("duplicate table column %q", column)
+		}
+		seen[column] = true
+	}
+	g.tableColumns = append([]string(nil), columns...)
+	return nil
+}
+
+// WithTableSortKey sets the column AssertTable sorts the rows by. An empty
+// key keeps the order of the rows.
+func (g *Golden) WithTableSortKey(column string) error {
+	g.tableSortKey = column
+	return nil
+}
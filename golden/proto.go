@@ -0,0 +1,476 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// ProtoFormat is used to enumerate the formats protocol buffer messages can be
+// stored in.
+type ProtoFormat int
+
+// noinspection GoUnusedConst
+const (
+	// ProtoText stores the messages in the protocol buffer text format. The
+	// fields are ordered by field number and map entries by key.
+	ProtoText ProtoFormat = iota
+
+	// ProtoJSON stores the messages in the canonical protocol buffer JSON
+	// mapping, indented with two spaces.
+	ProtoJSON
+)
+
+// AssertProto compares the actual protocol buffer message received with the
+// expected message in the golden files. If the update flag is set, it will
+// also update the golden file.
+//
+// The message is stored in a deterministic form selected by WithProtoFormat.
+// The comparison is semantic: fields set to their default value are equal to
+// unset fields. Unknown fields are not compared at all, rather than compared
+// regardless of their order: neither format can store them, so a message
+// carrying unknown fields could never match its own fixture. On mismatch, the
+// differences are reported by field path.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertProto(t *testing.T, name string, actualMessage proto.Message, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	data, err := g.marshalProto(actualMessage)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if *update {
+		contentType := "text/x-protobuf"
+		if g.protoFormat == ProtoJSON {
+			contentType = "application/json"
+		}
+
+		err := g.withContentType(contentType).Update(t, name, data)
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+	}
+
+	report(t, g.conclude(t, name, g.GoldenFileName, g.compareProto(t, name, actualMessage)))
+}
+
+// marshalProto returns the deterministic representation of the message.
+func (g *Golden) marshalProto(m proto.Message) ([]byte, error) {
+	if g.protoFormat == ProtoJSON {
+		js, err := protojson.Marshal(m)
+		if err != nil {
+			return nil, err
+		}
+
+		// protojson randomizes insignificant whitespace on purpose
+		var compact, indented bytes.Buffer
+		if err := json.Compact(&compact, js); err != nil {
+			return nil, err
+		}
+		if err := json.Indent(&indented, compact.Bytes(), "", "  "); err != nil {
+			return nil, err
+		}
+		indented.WriteByte('\n')
+		return indented.Bytes(), nil
+	}
+
+	w := protoTextWriter{}
+	w.message(m.ProtoReflect())
+	return w.buf.Bytes(), nil
+}
+
+// unmarshalProto parses the golden fixture into the message.
+func (g *Golden) unmarshalProto(data []byte, m proto.Message) error {
+	if g.protoFormat == ProtoJSON {
+		return protojson.Unmarshal(data, m)
+	}
+	return prototext.Unmarshal(data, m)
+}
+
+// compareProto is reading the golden fixture file and compare the stored
+// message with the actual message.
+func (g *Golden) compareProto(t *testing.T, name string, actualMessage proto.Message) error {
+	data, err := readFixture(g.resolveFixture(t, name, g.GoldenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newErrFixtureNotFound()
+		}
+
+		return fmt.Errorf("expected %s to be nil", err.Error())
+	}
+
+	actual := actualMessage.ProtoReflect()
+	expected := actual.New()
+	if err := g.unmarshalProto(g.normalize(data), expected.Interface()); err != nil {
+		return fmt.Errorf("could not parse the golden fixture as %s: %w", actual.Descriptor().FullName(), err)
+	}
+
+	diffs := diffProtoMessage("", expected, actual)
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	msg := "Result did not match the golden fixture. Differences are below:\n\n"
+	msg += strings.Join(diffs, "\n")
+	return newErrFixtureMismatch(msg)
+}
+
+// diffProtoMessage returns the differences between the two messages, one line
+// per field path. Unset fields are treated as fields set to the default value,
+// unknown fields are ignored.
+func diffProtoMessage(path string, expected, actual protoreflect.Message) []string {
+	var diffs []string
+
+	for _, fd := range protoFields(expected, actual) {
+		fieldPath := joinProtoPath(path, fd)
+		if !expected.Has(fd) && !actual.Has(fd) {
+			continue
+		}
+
+		switch {
+		case fd.IsList():
+			diffs = append(diffs, diffProtoList(fieldPath, fd, expected.Get(fd).List(), actual.Get(fd).List())...)
+		case fd.IsMap():
+			diffs = append(diffs, diffProtoMap(fieldPath, fd, expected.Get(fd).Map(), actual.Get(fd).Map())...)
+		default:
+			diffs = append(diffs, diffProtoValue(fieldPath, fd, expected.Get(fd), actual.Get(fd))...)
+		}
+	}
+
+	return diffs
+}
+
+// diffProtoList compares the elements of two repeated fields by index.
+func diffProtoList(path string, fd protoreflect.FieldDescriptor, expected, actual protoreflect.List) []string {
+	var diffs []string
+
+	for i := 0; i < expected.Len() || i < actual.Len(); i++ {
+		elemPath := fmt.Sprintf("%s[%d]", path, i)
+		switch {
+		case i >= actual.Len():
+			diffs = append(diffs, fmt.Sprintf("- %s: %s", elemPath, formatProtoValue(fd, expected.Get(i))))
+		case i >= expected.Len():
+			diffs = append(diffs, fmt.Sprintf("+ %s: %s", elemPath, formatProtoValue(fd, actual.Get(i))))
+		default:
+			diffs = append(diffs, diffProtoValue(elemPath, fd, expected.Get(i), actual.Get(i))...)
+		}
+	}
+
+	return diffs
+}
+
+// diffProtoMap compares the entries of two map fields by key.
+func diffProtoMap(path string, fd protoreflect.FieldDescriptor, expected, actual protoreflect.Map) []string {
+	var diffs []string
+
+	valueFd := fd.MapValue()
+	for _, key := range protoMapKeys(expected, actual) {
+		entryPath := fmt.Sprintf("%s[%s]", path, formatProtoValue(fd.MapKey(), key.Value()))
+		switch {
+		case !actual.Has(key):
+			diffs = append(diffs, fmt.Sprintf("- %s: %s", entryPath, formatProtoValue(valueFd, expected.Get(key))))
+		case !expected.Has(key):
+			diffs = append(diffs, fmt.Sprintf("+ %s: %s", entryPath, formatProtoValue(valueFd, actual.Get(key))))
+		default:
+			diffs = append(diffs, diffProtoValue(entryPath, valueFd, expected.Get(key), actual.Get(key))...)
+		}
+	}
+
+	return diffs
+}
+
+// diffProtoValue compares a singular value, recursing into messages.
+func diffProtoValue(path string, fd protoreflect.FieldDescriptor, expected, actual protoreflect.Value) []string {
+	if fd.Message() != nil {
+		return diffProtoMessage(path, expected.Message(), actual.Message())
+	}
+
+	if equalProtoScalar(expected.Interface(), actual.Interface()) {
+		return nil
+	}
+
+	return []string{fmt.Sprintf("~ %s: %s => %s", path, formatProtoValue(fd, expected), formatProtoValue(fd, actual))}
+}
+
+// equalProtoScalar compares two scalar values. NaN is equal to NaN.
+func equalProtoScalar(expected, actual interface{}) bool {
+	switch e := expected.(type) {
+	case []byte:
+		a, ok := actual.([]byte)
+		return ok && bytes.Equal(e, a)
+	case float32:
+		a, ok := actual.(float32)
+		return ok && (e == a || (math.IsNaN(float64(e)) && math.IsNaN(float64(a))))
+	case float64:
+		a, ok := actual.(float64)
+		return ok && (e == a || (math.IsNaN(e) && math.IsNaN(a)))
+	}
+	return expected == actual
+}
+
+// protoFields returns the descriptors of all known fields of the message type
+// and the extensions populated in either message, ordered by field number.
+func protoFields(messages ...protoreflect.Message) []protoreflect.FieldDescriptor {
+	fields := messages[0].Descriptor().Fields()
+	list := make([]protoreflect.FieldDescriptor, 0, fields.Len())
+	for i := 0; i < fields.Len(); i++ {
+		list = append(list, fields.Get(i))
+	}
+
+	seen := map[protoreflect.FullName]bool{}
+	for _, m := range messages {
+		m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+			if fd.IsExtension() && !seen[fd.FullName()] {
+				seen[fd.FullName()] = true
+				list = append(list, fd)
+			}
+			return true
+		})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Number() < list[j].Number()
+	})
+	return list
+}
+
+// populatedProtoFields returns the descriptors of the populated fields of the
+// message, ordered by field number.
+func populatedProtoFields(m protoreflect.Message) []protoreflect.FieldDescriptor {
+	var list []protoreflect.FieldDescriptor
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		list = append(list, fd)
+		return true
+	})
+
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Number() < list[j].Number()
+	})
+	return list
+}
+
+// protoMapKeys returns the union of the keys of the maps in sorted order.
+func protoMapKeys(maps ...protoreflect.Map) []protoreflect.MapKey {
+	var keys []protoreflect.MapKey
+	seen := map[interface{}]bool{}
+	for _, m := range maps {
+		m.Range(func(key protoreflect.MapKey, _ protoreflect.Value) bool {
+			if !seen[key.Interface()] {
+				seen[key.Interface()] = true
+				keys = append(keys, key)
+			}
+			return true
+		})
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		switch a := keys[i].Interface().(type) {
+		case bool:
+			return !a && keys[j].Bool()
+		case int32, int64:
+			return keys[i].Int() < keys[j].Int()
+		case uint32, uint64:
+			return keys[i].Uint() < keys[j].Uint()
+		}
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// protoFieldName returns the name of the field as used by the text format.
+func protoFieldName(fd protoreflect.FieldDescriptor) string {
+	switch {
+	case fd.IsExtension():
+		return "[" + string(fd.FullName()) + "]"
+	case fd.Kind() == protoreflect.GroupKind:
+		return string(fd.Message().Name())
+	}
+	return string(fd.Name())
+}
+
+// joinProtoPath appends the field name to the field path.
+func joinProtoPath(path string, fd protoreflect.FieldDescriptor) string {
+	if path == "" {
+		return protoFieldName(fd)
+	}
+	return path + "." + protoFieldName(fd)
+}
+
+// formatProtoValue returns a single line text representation of the value.
+func formatProtoValue(fd protoreflect.FieldDescriptor, v protoreflect.Value) string {
+	w := protoTextWriter{compact: true}
+	if fd.Message() == nil {
+		w.scalar(fd, v)
+		return w.buf.String()
+	}
+
+	w.buf.WriteString("{ ")
+	w.message(v.Message())
+	w.buf.WriteByte('}')
+	return w.buf.String()
+}
+
+// protoTextWriter writes messages in the protocol buffer text format. Unlike
+// prototext, the output is stable: fields are ordered by field number, map
+// entries by key and no whitespace is randomized. In compact mode all fields
+// are written on a single line.
+type protoTextWriter struct {
+	buf     bytes.Buffer
+	indent  int
+	compact bool
+}
+
+// message writes the populated fields of the message.
+func (w *protoTextWriter) message(m protoreflect.Message) {
+	for _, fd := range populatedProtoFields(m) {
+		v := m.Get(fd)
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				w.field(protoFieldName(fd), fd, list.Get(i))
+			}
+		case fd.IsMap():
+			entries := v.Map()
+			for _, key := range protoMapKeys(entries) {
+				w.mapEntry(protoFieldName(fd), fd, key, entries.Get(key))
+			}
+		default:
+			w.field(protoFieldName(fd), fd, v)
+		}
+	}
+}
+
+// field writes a single `name: value` or `name { ... }` field.
+func (w *protoTextWriter) field(name string, fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	w.writeIndent()
+	w.buf.WriteString(name)
+	if fd.Message() == nil {
+		w.buf.WriteString(": ")
+		w.scalar(fd, v)
+		w.newLine()
+		return
+	}
+
+	w.buf.WriteString(" {")
+	w.newLine()
+	w.indent++
+	w.message(v.Message())
+	w.indent--
+	w.writeIndent()
+	w.buf.WriteByte('}')
+	w.newLine()
+}
+
+// mapEntry writes a map entry as a message with a key and a value field.
+func (w *protoTextWriter) mapEntry(name string, fd protoreflect.FieldDescriptor, key protoreflect.MapKey, v protoreflect.Value) {
+	w.writeIndent()
+	w.buf.WriteString(name)
+	w.buf.WriteString(" {")
+	w.newLine()
+	w.indent++
+	w.field("key", fd.MapKey(), key.Value())
+	w.field("value", fd.MapValue(), v)
+	w.indent--
+	w.writeIndent()
+	w.buf.WriteByte('}')
+	w.newLine()
+}
+
+// scalar writes a value that is not a message.
+func (w *protoTextWriter) scalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) {
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			w.buf.WriteString(string(ev.Name()))
+		} else {
+			w.buf.WriteString(strconv.FormatInt(int64(v.Enum()), 10))
+		}
+	case protoreflect.StringKind:
+		w.buf.WriteString(quoteProtoText(v.String()))
+	case protoreflect.BytesKind:
+		w.buf.WriteString(quoteProtoText(string(v.Bytes())))
+	case protoreflect.FloatKind:
+		w.buf.WriteString(formatProtoFloat(v.Float(), 32))
+	case protoreflect.DoubleKind:
+		w.buf.WriteString(formatProtoFloat(v.Float(), 64))
+	default:
+		w.buf.WriteString(v.String())
+	}
+}
+
+// writeIndent indents a new field.
+func (w *protoTextWriter) writeIndent() {
+	if !w.compact {
+		w.buf.WriteString(strings.Repeat("  ", w.indent))
+	}
+}
+
+// newLine ends a field.
+func (w *protoTextWriter) newLine() {
+	if w.compact {
+		w.buf.WriteByte(' ')
+		return
+	}
+	w.buf.WriteByte('\n')
+}
+
+// quoteProtoText quotes the string using the escapes of the text format.
+func quoteProtoText(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			fmt.Fprintf(&b, `\%03o`, s[i])
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r < 0x20 || r == 0x7f:
+			fmt.Fprintf(&b, `\%03o`, r)
+		default:
+			b.WriteString(s[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// formatProtoFloat formats the float using the spelling of the text format.
+func formatProtoFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
@@ -0,0 +1,137 @@
+package golden
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func newProtoStruct(t *testing.T, v map[string]interface{}) *structpb.Struct {
+	t.Helper()
+	s, err := structpb.NewStruct(v)
+	require.NoError(t, err)
+	return s
+}
+
+func TestMarshalProtoIsDeterministic(t *testing.T) {
+	msg := newProtoStruct(t, map[string]interface{}{
+		"name":  "gorky",
+		"tags":  []interface{}{"a", "b"},
+		"count": 3,
+		"owner": map[string]interface{}{"id": "x\n\"y\""},
+	})
+
+	for _, format := range []ProtoFormat{ProtoText, ProtoJSON} {
+		g := New(t, WithProtoFormat(format))
+		first, err := g.marshalProto(msg)
+		require.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			again, err := g.marshalProto(proto.Clone(msg))
+			require.NoError(t, err)
+			assert.Equal(t, string(first), string(again))
+		}
+
+		parsed := &structpb.Struct{}
+		require.NoError(t, g.unmarshalProto(first, parsed))
+		assert.True(t, proto.Equal(msg, parsed))
+	}
+}
+
+func TestMarshalProtoText(t *testing.T) {
+	msg := &descriptorpb.FieldDescriptorProto{
+		Name:   proto.String("id"),
+		Number: proto.Int32(1),
+		Label:  descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		Options: &descriptorpb.FieldOptions{
+			Deprecated: proto.Bool(true),
+		},
+	}
+
+	g := New(t)
+	data, err := g.marshalProto(msg)
+	require.NoError(t, err)
+	assert.Equal(t, `name: "id"
+number: 1
+label: LABEL_OPTIONAL
+options {
+  deprecated: true
+}
+`, string(data))
+}
+
+func TestCompareProto(t *testing.T) {
+	expected := newProtoStruct(t, map[string]interface{}{
+		"name": "gorky",
+		"tags": []interface{}{"a", "b"},
+	})
+
+	g := New(t)
+	data, err := g.marshalProto(expected)
+	require.NoError(t, err)
+	require.NoError(t, g.Update(t, "proto", data))
+	defer func() {
+		assert.Nil(t, os.RemoveAll(g.fixtureDir))
+	}()
+
+	assert.Nil(t, g.compareProto(t, "proto", expected))
+
+	actual := newProtoStruct(t, map[string]interface{}{
+		"name": "bitter",
+		"tags": []interface{}{"a", "b", "c"},
+		"new":  true,
+	})
+	err = g.compareProto(t, "proto", actual)
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), `~ fields["name"].string_value: "gorky" => "bitter"`)
+	assert.Contains(t, err.Error(), `+ fields["tags"].list_value.values[2]: { string_value: "c" }`)
+	assert.Contains(t, err.Error(), `+ fields["new"]: { bool_value: true }`)
+
+	err = g.compareProto(t, "missing", actual)
+	assert.IsType(t, &errFixtureNotFound{}, err)
+}
+
+func TestDiffProtoIgnoresDefaultValues(t *testing.T) {
+	withDefaults := &descriptorpb.FieldDescriptorProto{
+		Name:           proto.String("id"),
+		JsonName:       proto.String(""),
+		Proto3Optional: proto.Bool(false),
+	}
+	withoutDefaults := &descriptorpb.FieldDescriptorProto{
+		Name: proto.String("id"),
+	}
+
+	assert.False(t, proto.Equal(withDefaults, withoutDefaults))
+	assert.Empty(t, diffProtoMessage("", withDefaults.ProtoReflect(), withoutDefaults.ProtoReflect()))
+}
+
+func TestCompareProtoIgnoresUnknownFields(t *testing.T) {
+	var unknown []byte
+	unknown = protowire.AppendTag(unknown, 100, protowire.VarintType)
+	unknown = protowire.AppendVarint(unknown, 1)
+
+	msg := wrapperspb.String("gorky")
+	msg.ProtoReflect().SetUnknown(unknown)
+
+	for _, format := range []ProtoFormat{ProtoText, ProtoJSON} {
+		g := New(t, WithFixtureDir(t.TempDir()), WithProtoFormat(format))
+		data, err := g.marshalProto(msg)
+		require.NoError(t, err)
+		require.NoError(t, g.Update(t, "proto", data))
+
+		// the fixture can't store the unknown fields, so they are not compared
+		assert.Nil(t, g.compareProto(t, "proto", msg))
+	}
+}
+
+func TestQuoteProtoText(t *testing.T) {
+	assert.Equal(t, `"a\"b\\c\n\t\001é"`, quoteProtoText("a\"b\\c\n\t\x01é"))
+	assert.Equal(t, `"\377"`, quoteProtoText("\xff"))
+}
@@ -0,0 +1,449 @@
+package golden
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const (
+	// streamChunkSize is the number of bytes AssertReader compares at once.
+	streamChunkSize = 32 * 1024
+
+	// streamContextLines is the number of lines shown before and after the
+	// first difference found by AssertReader.
+	streamContextLines = 3
+
+	// streamContextBytes bounds the context shown before and after the first
+	// difference, so very long lines do not blow up the diff.
+	streamContextBytes = 1024
+)
+
+// AssertReader compares the data read from `actual` with the expected data in
+// the golden files chunk by chunk, so large outputs never have to be held in
+// memory. The comparison stops at the first difference and the diff only
+// shows the lines around it. If the update flag is set, the data is streamed
+// to the golden file instead of being compared.
+//
+// Line endings, WithStripBOM, compression, fixture variants, content hashes
+// and metadata headers are handled like Assert does. Options that need the
+// whole output, i.e. the line rules, WithUTF16Decoding and WithTrailingNewline,
+// are not supported and fail the test.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertReader(t *testing.T, name string, actual io.Reader, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	if err := g.checkStreamable(); err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	if *update {
+		// the data is consumed by the update, there's nothing left to compare
+		err := g.conclude(t, name, g.GoldenFileName, g.updateReader(t, name, actual))
+		if err != nil {
+			t.Error(err)
+			t.FailNow()
+		}
+		return
+	}
+
+	report(t, g.conclude(t, name, g.GoldenFileName, g.compareReader(t, name, actual)))
+}
+
+// checkStreamable returns an error if an option requires the whole output.
+func (g *Golden) checkStreamable() error {
+	switch {
+	case g.hasLineRules():
+		return errors.New("AssertReader does not support unordered, ignored or whitespace insensitive lines")
+	case g.decodeUTF16:
+		return errors.New("AssertReader does not support WithUTF16Decoding")
+	case g.trailingNewline != PreserveTrailingNewline:
+		return errors.New("AssertReader does not support WithTrailingNewline")
+	}
+	return nil
+}
+
+// compareReader streams the golden fixture and compares it with the actual
+// data.
+func (g *Golden) compareReader(t *testing.T, name string, actual io.Reader) error {
+	fixture, err := openFixture(g.resolveFixture(t, name, g.GoldenFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return newErrFixtureNotFound()
+		}
+
+		return fmt.Errorf("expected %s to be nil", err.Error())
+	}
+	defer fixture.Close()
+
+	if g.contentHash {
+		return g.compareDigestReader(t, name, actual, fixture)
+	}
+
+	return g.compareStreams(g.normalizeReader(actual), g.normalizeReader(fixture))
+}
+
+// compareDigestReader compares the digest stored in the golden fixture with
+// the digest of the streamed data. The data is saved to the artifacts
+// directory while it's hashed and removed again if it matches.
+func (g *Golden) compareDigestReader(t *testing.T, name string, actual io.Reader, fixture io.Reader) error {
+	data, err := io.ReadAll(fixture)
+	if err != nil {
+		return err
+	}
+	expected, err := parseDigest(data)
+	if err != nil {
+		return err
+	}
+
+	var artifact *os.File
+	path := g.ArtifactFileName(t, name)
+	err = os.MkdirAll(filepath.Dir(path), g.dirPerms)
+	if err == nil {
+		artifact, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, g.filePerms)
+	}
+
+	w := newDigestWriter()
+	if artifact != nil {
+		_, copyErr := io.Copy(io.MultiWriter(w, artifact), actual)
+		closeErr := artifact.Close()
+		if copyErr != nil {
+			return copyErr
+		}
+		err = closeErr
+	} else if _, copyErr := io.Copy(w, actual); copyErr != nil {
+		return copyErr
+	}
+
+	got := w.digest()
+	if got.sum == expected.sum && got.size == expected.size {
+		if artifact != nil {
+			return os.Remove(path)
+		}
+		return nil
+	}
+
+	return newDigestMismatch(expected, got, path, err)
+}
+
+// compareStreams compares both streams chunk by chunk and returns an
+// errFixtureMismatch showing the lines around the first difference.
+func (g *Golden) compareStreams(actual io.Reader, expected io.Reader) error {
+	d, err := findDifference(actual, expected)
+	if err != nil || d == nil {
+		return err
+	}
+
+	return g.newStreamMismatch(d)
+}
+
+// streamDifference is the first difference between two streams.
+type streamDifference struct {
+	// offset is the position of the first differing byte.
+	offset int64
+	// line is the line number of the first differing byte.
+	line int
+	// actual and expected are the lines around the difference.
+	actual   string
+	expected string
+}
+
+// findDifference compares both streams chunk by chunk and returns the first
+// difference, or nil if they are equal.
+func findDifference(actual io.Reader, expected io.Reader) (*streamDifference, error) {
+	actualChunk := make([]byte, streamChunkSize)
+	expectedChunk := make([]byte, streamChunkSize)
+	var before []byte
+	var offset int64
+	line := 1
+
+	for {
+		na, actualErr := io.ReadFull(actual, actualChunk)
+		if actualErr != nil && actualErr != io.EOF && actualErr != io.ErrUnexpectedEOF {
+			return nil, actualErr
+		}
+		ne, expectedErr := io.ReadFull(expected, expectedChunk)
+		if expectedErr != nil && expectedErr != io.EOF && expectedErr != io.ErrUnexpectedEOF {
+			return nil, expectedErr
+		}
+
+		n := na
+		if ne < n {
+			n = ne
+		}
+		i := 0
+		for i < n && actualChunk[i] == expectedChunk[i] {
+			i++
+		}
+
+		if i < n || na != ne {
+			before = append(before, actualChunk[:i]...)
+			actualAfter, err := contextAfter(actualChunk[i:na], actual)
+			if err != nil {
+				return nil, err
+			}
+			expectedAfter, err := contextAfter(expectedChunk[i:ne], expected)
+			if err != nil {
+				return nil, err
+			}
+
+			context := string(contextBefore(before))
+			return &streamDifference{
+				offset:   offset + int64(i),
+				line:     line + bytes.Count(actualChunk[:i], []byte{'\n'}),
+				actual:   context + string(actualAfter),
+				expected: context + string(expectedAfter),
+			}, nil
+		}
+
+		if actualErr != nil {
+			return nil, nil
+		}
+
+		offset += int64(n)
+		line += bytes.Count(actualChunk[:n], []byte{'\n'})
+		before = append(before, actualChunk[:n]...)
+		if len(before) > streamContextBytes {
+			before = before[len(before)-streamContextBytes:]
+		}
+	}
+}
+
+// newStreamMismatch returns an errFixtureMismatch whose message contains the
+// position of the first difference and the diff of the lines around it.
+func (g *Golden) newStreamMismatch(d *streamDifference) error {
+	msg := fmt.Sprintf(
+		"Result did not match the golden fixture at byte %d (line %d). Diff of the surrounding lines is below:\n\n",
+		d.offset, d.line,
+	)
+	msg += g.diff(d.actual, d.expected)
+
+	return newErrFixtureMismatch(msg)
+}
+
+// contextBefore returns the start of the line containing the first difference
+// and up to streamContextLines lines before it, bounded to
+// streamContextBytes.
+func contextBefore(data []byte) []byte {
+	if len(data) > streamContextBytes {
+		data = data[len(data)-streamContextBytes:]
+	}
+
+	end := len(data)
+	for lines := 0; lines <= streamContextLines; lines++ {
+		i := bytes.LastIndexByte(data[:end], '\n')
+		if i < 0 {
+			return data
+		}
+		end = i
+	}
+	return data[end+1:]
+}
+
+// contextAfter returns the rest of the line containing the first difference
+// and up to streamContextLines lines after it, bounded to streamContextBytes.
+// The remaining data is read from r as needed.
+func contextAfter(data []byte, r io.Reader) ([]byte, error) {
+	context := append([]byte(nil), data...)
+	if len(context) < streamContextBytes {
+		more := make([]byte, streamContextBytes-len(context))
+		n, err := io.ReadFull(r, more)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, err
+		}
+		context = append(context, more[:n]...)
+	}
+	if len(context) > streamContextBytes {
+		context = context[:streamContextBytes]
+	}
+
+	start := 0
+	for lines := 0; lines <= streamContextLines; lines++ {
+		i := bytes.IndexByte(context[start:], '\n')
+		if i < 0 {
+			return context, nil
+		}
+		start += i + 1
+	}
+	return context[:start], nil
+}
+
+// updateReader streams the data to the golden file. The data is written to a
+// temporary file next to the fixture first, so it can be compared with the
+// fixture variants and the existing metadata header without holding it in
+// memory.
+func (g *Golden) updateReader(t *testing.T, name string, actual io.Reader) error {
+	dir := filepath.Dir(g.GoldenFileName(t, name))
+	if err := g.ensureDir(dir); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".golden-stream-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if g.contentHash {
+		w := newDigestWriter()
+		if _, err = io.Copy(w, actual); err == nil {
+			_, err = tmp.Write(w.digest().bytes())
+		}
+	} else {
+		_, err = io.Copy(tmp, g.normalizeReader(actual))
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	target, err := g.updateTargetFn(t, name, g.GoldenFileName, func(fallback string) (bool, error) {
+		return equalFiles(tmp.Name(), fallback, g.normalizeReader)
+	})
+	if err != nil || target == "" {
+		return err
+	}
+
+	var header []byte
+	if g.metadata {
+		unchanged, err := equalFiles(tmp.Name(), target, nil)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		leading, err := readLeading(tmp.Name(), 512)
+		if err != nil {
+			return err
+		}
+		header = g.newMetadata(t, target, leading, unchanged).header()
+	}
+
+	if err := g.writeFixtureFrom(target, header, tmp.Name()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(filepath.Dir(target), ts, ts)
+}
+
+// equalFiles reports whether the content of the data file equals the content
+// of the golden fixture. normalize is applied to the fixture and may be nil.
+// The error of os.Open is returned untouched.
+func equalFiles(data string, fixture string, normalize func(io.Reader) io.Reader) (bool, error) {
+	f, err := openFixture(fixture)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	d, err := os.Open(data)
+	if err != nil {
+		return false, err
+	}
+	defer d.Close()
+
+	var expected io.Reader = f
+	if normalize != nil {
+		expected = normalize(f)
+	}
+
+	diff, err := findDifference(d, expected)
+	return diff == nil && err == nil, err
+}
+
+// readLeading returns up to n leading bytes of the file.
+func readLeading(path string, n int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	leading := make([]byte, n)
+	read, err := io.ReadFull(f, leading)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	return leading[:read], nil
+}
+
+// normalizeReader applies the line ending policy and WithStripBOM to streamed
+// data, like normalize does for Assert.
+func (g *Golden) normalizeReader(r io.Reader) io.Reader {
+	if g.stripBOM {
+		br := bufio.NewReader(r)
+		if bom, err := br.Peek(len(utf8BOM)); err == nil && bytes.Equal(bom, utf8BOM) {
+			_, _ = br.Discard(len(utf8BOM))
+		}
+		r = br
+	}
+
+	switch g.lineEnding {
+	case LF:
+		return &newlineReader{r: r, newline: []byte{10}}
+	case CRLF:
+		return &newlineReader{r: r, newline: []byte{13, 10}}
+	}
+	return r
+}
+
+// newlineReader converts \r\n, \r and \n line endings of the streamed data
+// into the given newline. A \r at the end of a chunk is held back until the
+// next byte tells whether it's part of a \r\n.
+type newlineReader struct {
+	r       io.Reader
+	newline []byte
+	chunk   []byte
+	out     bytes.Buffer
+	cr      bool
+	err     error
+}
+
+// Read implements io.Reader.
+func (n *newlineReader) Read(p []byte) (int, error) {
+	for n.out.Len() == 0 {
+		if n.err != nil {
+			return 0, n.err
+		}
+		if n.chunk == nil {
+			n.chunk = make([]byte, streamChunkSize)
+		}
+
+		read, err := n.r.Read(n.chunk)
+		n.err = err
+		for _, c := range n.chunk[:read] {
+			switch {
+			case c == '\r':
+				if n.cr {
+					n.out.Write(n.newline)
+				}
+				n.cr = true
+			case c == '\n':
+				n.out.Write(n.newline)
+				n.cr = false
+			default:
+				if n.cr {
+					n.out.Write(n.newline)
+					n.cr = false
+				}
+				n.out.WriteByte(c)
+			}
+		}
+		if err != nil && n.cr {
+			n.out.Write(n.newline)
+			n.cr = false
+		}
+	}
+
+	return n.out.Read(p)
+}
@@ -0,0 +1,181 @@
+package golden
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// numberedLines returns n lines of the form `line <i>`.
+func numberedLines(n int) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+	return b.String()
+}
+
+func TestFindDifference(t *testing.T) {
+	data := numberedLines(10000)
+
+	d, err := findDifference(strings.NewReader(data), strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Nil(t, d)
+
+	changed := strings.Replace(data, "line 5000\n", "line 5000 changed\n", 1)
+	d, err = findDifference(strings.NewReader(changed), strings.NewReader(data))
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, int64(strings.Index(data, "line 5000\n")+len("line 5000")), d.offset)
+	assert.Equal(t, 5000, d.line)
+	assert.Equal(t, "line 4997\nline 4998\nline 4999\nline 5000 changed\nline 5001\nline 5002\nline 5003\n", d.actual)
+	assert.Equal(t, "line 4997\nline 4998\nline 4999\nline 5000\nline 5001\nline 5002\nline 5003\n", d.expected)
+
+	// a shorter output differs at its end
+	d, err = findDifference(strings.NewReader("a\nb\n"), strings.NewReader("a\nb\nc\n"))
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Equal(t, int64(4), d.offset)
+	assert.Equal(t, 3, d.line)
+	assert.Equal(t, "a\nb\n", d.actual)
+	assert.Equal(t, "a\nb\nc\n", d.expected)
+
+	// read errors are returned
+	_, err = findDifference(iotest.ErrReader(io.ErrClosedPipe), strings.NewReader(data))
+	assert.Equal(t, io.ErrClosedPipe, err)
+}
+
+func TestContextIsBounded(t *testing.T) {
+	long := strings.Repeat("x", 10*streamContextBytes)
+	d, err := findDifference(strings.NewReader(long+"a"+long), strings.NewReader(long+"b"+long))
+	require.NoError(t, err)
+	require.NotNil(t, d)
+	assert.Len(t, d.actual, 2*streamContextBytes)
+	assert.Len(t, d.expected, 2*streamContextBytes)
+}
+
+func TestNewlineReader(t *testing.T) {
+	tests := map[string]struct {
+		newline  []byte
+		input    string
+		expected string
+	}{
+		"lf":   {newline: []byte("\n"), input: "a\r\nb\rc\n\r\rd\r", expected: "a\nb\nc\n\n\nd\n"},
+		"crlf": {newline: []byte("\r\n"), input: "a\r\nb\rc\n", expected: "a\r\nb\r\nc\r\n"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			// one byte reads split \r\n across chunks
+			r := &newlineReader{r: iotest.OneByteReader(strings.NewReader(test.input)), newline: test.newline}
+			data, err := io.ReadAll(r)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, string(data))
+		})
+	}
+}
+
+func TestAssertReader(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir))
+	data := numberedLines(20000)
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertReader(t, "export", strings.NewReader(strings.Replace(data, "\n", "\r\n", -1)))
+	*update = savedUpdateState
+
+	fixture, err := os.ReadFile(filepath.Join(dir, "export.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, data, string(fixture))
+
+	g.AssertReader(t, "export", strings.NewReader(data))
+
+	err = g.compareReader(t, "export", strings.NewReader(strings.Replace(data, "line 12345\n", "line 12345!\n", 1)))
+	require.IsType(t, &errFixtureMismatch{}, err)
+	assert.Contains(t, err.Error(), "(line 12345)")
+	assert.Contains(t, err.Error(), "-line 12345\n+line 12345!\n")
+
+	assert.IsType(t, &errFixtureNotFound{}, g.compareReader(t, "missing", strings.NewReader(data)))
+}
+
+func TestAssertReaderCompressionAndMetadata(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithCompression(Gzip), WithMetadata(true))
+	data := []byte(numberedLines(100))
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertReader(t, "export", bytes.NewReader(data))
+	*update = savedUpdateState
+
+	fixture := filepath.Join(dir, "export.golden.gz")
+	stored, err := readFixture(fixture)
+	require.NoError(t, err)
+	assert.Equal(t, data, stored)
+
+	m, err := ReadMetadata(fixture)
+	require.NoError(t, err)
+	require.NotNil(t, m)
+	assert.Equal(t, t.Name(), m.Test)
+	assert.Equal(t, "text/plain; charset=utf-8", m.ContentType)
+
+	assert.NoError(t, g.compareReader(t, "export", bytes.NewReader(data)))
+	assert.IsType(t, &errFixtureMismatch{}, g.compareReader(t, "export", strings.NewReader("other\n")))
+}
+
+func TestAssertReaderVariants(t *testing.T) {
+	dir := t.TempDir()
+	generic := New(t, WithFixtureDir(dir))
+	variant := New(t, WithFixtureDir(dir), WithFixtureVariants("linux"))
+	specific := filepath.Join(dir, "export.linux.golden")
+	require.NoError(t, generic.Update(t, "export", []byte("generic\n")))
+
+	require.NoError(t, variant.updateReader(t, "export", strings.NewReader("generic\n")))
+	assert.NoFileExists(t, specific)
+
+	require.NoError(t, variant.updateReader(t, "export", strings.NewReader("linux\n")))
+	assert.FileExists(t, specific)
+	assert.NoError(t, variant.compareReader(t, "export", strings.NewReader("linux\n")))
+	assert.NoError(t, generic.compareReader(t, "export", strings.NewReader("generic\n")))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 2, "the temporary file must be removed")
+}
+
+func TestAssertReaderContentHash(t *testing.T) {
+	dir := t.TempDir()
+	artifacts := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithContentHash(true), WithArtifactsDir(artifacts))
+	data := numberedLines(1000)
+
+	require.NoError(t, g.updateReader(t, "export", strings.NewReader(data)))
+	fixture, err := os.ReadFile(filepath.Join(dir, "export.golden"))
+	require.NoError(t, err)
+	assert.Equal(t, newDigest([]byte(data)).bytes(), fixture)
+
+	assert.NoError(t, g.compareReader(t, "export", strings.NewReader(data)))
+	assert.NoFileExists(t, g.ArtifactFileName(t, "export"))
+
+	err = g.compareReader(t, "export", strings.NewReader("other\n"))
+	require.IsType(t, &errFixtureMismatch{}, err)
+	artifact, err := os.ReadFile(g.ArtifactFileName(t, "export"))
+	require.NoError(t, err)
+	assert.Equal(t, "other\n", string(artifact))
+}
+
+func TestCheckStreamable(t *testing.T) {
+	assert.NoError(t, New(t).checkStreamable())
+	assert.Error(t, New(t, WithUnorderedLines(true)).checkStreamable())
+	assert.Error(t, New(t, WithUTF16Decoding(true)).checkStreamable())
+	assert.Error(t, New(t, WithTrailingNewline(EnsureTrailingNewline)).checkStreamable())
+}
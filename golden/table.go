@@ -0,0 +1,457 @@
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// TableFormat is used to enumerate the formats AssertTable can render the
+// rows in.
+type TableFormat int
+
+// noinspection GoUnusedConst
+const (
+	// TableMarkdown renders the rows as an aligned Markdown table.
+	TableMarkdown TableFormat = iota
+
+	// TablePlain renders the rows as an aligned plain text table.
+	TablePlain
+)
+
+// table is a rendered snapshot of structured rows.
+type table struct {
+	columns []string
+	rows    [][]string
+	// values are the original cell values, used to sort the rows.
+	values [][]interface{}
+}
+
+// AssertTable renders the rows as a table and compares it with the table in
+// the golden files. If the update flag is set, it will also update the golden
+// file.
+//
+// `rows` must be a slice or array of structs, struct pointers or maps with
+// string keys. The columns of structs follow the order of the exported fields,
+// named after their json tag if they have one. The columns of maps are sorted
+// by name. The columns can be picked and ordered with WithTableColumns, the
+// rows sorted with WithTableSortKey and the format selected with
+// WithTableFormat.
+//
+// `name` refers to the name of the test and it should typically be unique
+// within the package. Also it should be a valid file name (so keeping to
+// `a-z0-9\-\_` is a good idea).
+func (g *Golden) AssertTable(t *testing.T, name string, rows interface{}, opts ...Option) {
+	t.Helper()
+	g = g.with(t, opts)
+	data, err := g.renderTable(rows)
+	if err != nil {
+		t.Error(err)
+		t.FailNow()
+	}
+
+	contentType := "text/markdown; charset=utf-8"
+	if g.tableFormat == TablePlain {
+		contentType = "text/plain; charset=utf-8"
+	}
+
+	g.withContentType(contentType).Assert(t, name, data)
+}
+
+// renderTable converts the rows into a table, selects its columns, sorts its
+// rows and renders it.
+func (g *Golden) renderTable(rows interface{}) ([]byte, error) {
+	tbl, err := newTable(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if g.tableSortKey != "" {
+		column := tbl.index(g.tableSortKey)
+		if column < 0 {
+			return nil, fmt.Errorf("unknown table sort key %q", g.tableSortKey)
+		}
+		tbl.sortBy(column)
+	}
+
+	if len(g.tableColumns) > 0 {
+		if tbl, err = tbl.selectColumns(g.tableColumns); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf bytes.Buffer
+	if g.tableFormat == TablePlain {
+		writeAlignedRows(&buf, tbl.columns, tbl.rows)
+	} else {
+		writeMarkdownTable(&buf, tbl.columns, tbl.rows)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// newTable reads the columns and cells of the rows.
+func newTable(rows interface{}) (*table, error) {
+	v := reflect.ValueOf(rows)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, fmt.Errorf("table rows must be a slice or an array, got %T", rows)
+	}
+
+	tbl := &table{}
+	switch elemType := indirectType(v.Type().Elem()); elemType.Kind() {
+	case reflect.Struct:
+		tbl.columns = structColumns(elemType)
+	case reflect.Interface:
+		columns, err := dynamicColumns(v)
+		if err != nil {
+			return nil, err
+		}
+		tbl.columns = columns
+	default:
+		tbl.columns = mapColumns(v)
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		values, err := rowValues(v.Index(i), tbl.columns)
+		if err != nil {
+			return nil, fmt.Errorf("table row %d: %w", i, err)
+		}
+
+		cells := make([]string, len(values))
+		for j, value := range values {
+			cells[j] = formatCell(value)
+		}
+		tbl.rows = append(tbl.rows, cells)
+		tbl.values = append(tbl.values, values)
+	}
+
+	return tbl, nil
+}
+
+// indirectType returns the type pointers point to.
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+// indirect returns the value behind pointers and interfaces, or an invalid
+// value for nil.
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+// structColumns returns the column names of the exported fields of a struct.
+func structColumns(typ reflect.Type) []string {
+	var columns []string
+	for i := 0; i < typ.NumField(); i++ {
+		if name, ok := fieldColumn(typ.Field(i)); ok {
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// fieldColumn returns the column name of a struct field. Unexported fields
+// and fields tagged `json:"-"` have no column.
+func fieldColumn(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch tag {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	}
+	return tag, true
+}
+
+// mapColumns returns the sorted union of the keys of all rows that are maps.
+func mapColumns(rows reflect.Value) []string {
+	seen := map[string]bool{}
+	var columns []string
+	for i := 0; i < rows.Len(); i++ {
+		row := indirect(rows.Index(i))
+		if row.Kind() != reflect.Map {
+			continue
+		}
+		for _, key := range row.MapKeys() {
+			if name := fmt.Sprint(key.Interface()); !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// dynamicColumns returns the columns of rows held in interfaces: the fields of
+// the structs in the order they appear, or the sorted keys of the maps.
+func dynamicColumns(rows reflect.Value) ([]string, error) {
+	seen := map[string]bool{}
+	var columns []string
+	var structs, maps bool
+	for i := 0; i < rows.Len(); i++ {
+		row := indirect(rows.Index(i))
+		switch row.Kind() {
+		case reflect.Struct:
+			structs = true
+			for _, name := range structColumns(row.Type()) {
+				if !seen[name] {
+					seen[name] = true
+					columns = append(columns, name)
+				}
+			}
+		case reflect.Map:
+			maps = true
+		}
+	}
+
+	if structs && maps {
+		return nil, errors.New("table rows must be either structs or maps, not both")
+	}
+	if maps {
+		return mapColumns(rows), nil
+	}
+	return columns, nil
+}
+
+// rowValues returns the values of the columns of a single row. Missing map
+// keys and nil rows give nil values.
+func rowValues(row reflect.Value, columns []string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	row = indirect(row)
+
+	switch row.Kind() {
+	case reflect.Invalid:
+
+	case reflect.Struct:
+		typ := row.Type()
+		fields := map[string]int{}
+		for i := 0; i < typ.NumField(); i++ {
+			if name, ok := fieldColumn(typ.Field(i)); ok {
+				fields[name] = i
+			}
+		}
+		for i, column := range columns {
+			if field, ok := fields[column]; ok {
+				values[i] = row.Field(field).Interface()
+			}
+		}
+
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("map keys must be strings, got %s", row.Type().Key())
+		}
+		for i, column := range columns {
+			value := row.MapIndex(reflect.ValueOf(column).Convert(row.Type().Key()))
+			if value.IsValid() {
+				values[i] = value.Interface()
+			}
+		}
+
+	default:
+		return nil, fmt.Errorf("rows must be structs or maps, got %s", row.Type())
+	}
+
+	return values, nil
+}
+
+// formatCell renders a cell value. Nil values are empty, nested values are
+// rendered as compact JSON.
+func formatCell(value interface{}) string {
+	v := indirect(reflect.ValueOf(value))
+	if !v.IsValid() {
+		return ""
+	}
+
+	switch value := v.Interface().(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	}
+
+	// methods with a pointer receiver are lost once the value is indirected
+	if stringer, ok := value.(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+		if js, err := json.Marshal(v.Interface()); err == nil {
+			return string(js)
+		}
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// index returns the index of the column or -1.
+func (tbl *table) index(column string) int {
+	for i, c := range tbl.columns {
+		if c == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// sortBy sorts the rows by the values of the column. Numbers are compared by
+// value, everything else by the rendered cell. Rows with equal keys keep their
+// order.
+func (tbl *table) sortBy(column int) {
+	order := make([]int, len(tbl.rows))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := order[i], order[j]
+		x, xOK := tableNumber(tbl.values[a][column])
+		y, yOK := tableNumber(tbl.values[b][column])
+		if xOK && yOK {
+			return x < y
+		}
+		return tbl.rows[a][column] < tbl.rows[b][column]
+	})
+
+	rows := make([][]string, len(order))
+	values := make([][]interface{}, len(order))
+	for i, k := range order {
+		rows[i], values[i] = tbl.rows[k], tbl.values[k]
+	}
+	tbl.rows, tbl.values = rows, values
+}
+
+// tableNumber returns the numeric value of a cell value.
+func tableNumber(value interface{}) (float64, bool) {
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	if n, ok := value.(json.Number); ok {
+		f, err := strconv.ParseFloat(string(n), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// selectColumns returns a table with only the columns, in their order.
+func (tbl *table) selectColumns(columns []string) (*table, error) {
+	indexes := make([]int, len(columns))
+	for i, column := range columns {
+		if indexes[i] = tbl.index(column); indexes[i] < 0 {
+			return nil, fmt.Errorf("unknown table column %q", column)
+		}
+	}
+
+	selected := &table{columns: columns}
+	for k, row := range tbl.rows {
+		cells := make([]string, len(indexes))
+		values := make([]interface{}, len(indexes))
+		for i, index := range indexes {
+			cells[i], values[i] = row[index], tbl.values[k][index]
+		}
+		selected.rows = append(selected.rows, cells)
+		selected.values = append(selected.values, values)
+	}
+
+	return selected, nil
+}
+
+// columnWidths returns the width of every column in runes.
+func columnWidths(columns []string, rows [][]string, minWidth int) []int {
+	widths := make([]int, len(columns))
+	for i, column := range columns {
+		widths[i] = utf8.RuneCountInString(column)
+		if widths[i] < minWidth {
+			widths[i] = minWidth
+		}
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	return widths
+}
+
+// writeMarkdownTable renders the rows as a Markdown table whose columns are
+// padded to the same width, so the rows line up in the golden file.
+func writeMarkdownTable(buf *bytes.Buffer, columns []string, rows [][]string) {
+	header := escapeCells(columns)
+	escaped := make([][]string, len(rows))
+	for i, row := range rows {
+		escaped[i] = escapeCells(row)
+	}
+
+	// the delimiter row needs at least three dashes
+	widths := columnWidths(header, escaped, 3)
+
+	writeRow := func(cells []string) {
+		buf.WriteByte('|')
+		for i, cell := range cells {
+			buf.WriteByte(' ')
+			buf.WriteString(cell)
+			buf.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			buf.WriteString(" |")
+		}
+		buf.WriteByte('\n')
+	}
+
+	writeRow(header)
+	separator := make([]string, len(widths))
+	for i, width := range widths {
+		separator[i] = strings.Repeat("-", width)
+	}
+	writeRow(separator)
+	for _, row := range escaped {
+		writeRow(row)
+	}
+}
+
+// cellReplacer escapes the characters that would break a table cell, in
+// Markdown and in plain text alike.
+var cellReplacer = strings.NewReplacer(`|`, `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+// escapeCells escapes the cells of a table row.
+func escapeCells(cells []string) []string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = cellReplacer.Replace(cell)
+	}
+	return escaped
+}
@@ -0,0 +1,144 @@
+package golden
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type tableUser struct {
+	ID     int      `json:"id"`
+	Name   string   `json:"name"`
+	Email  string   `json:"email,omitempty"`
+	Tags   []string `json:"tags"`
+	Secret string   `json:"-"`
+	Note   *string
+	hidden bool
+}
+
+func renderTableString(t *testing.T, rows interface{}, opts ...Option) string {
+	t.Helper()
+	data, err := New(t, opts...).renderTable(rows)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestRenderTable(t *testing.T) {
+	note := "a | b\nc"
+	users := []tableUser{
+		{ID: 10, Name: "gopher", Email: "gopher@example.com", Tags: []string{"a"}},
+		{ID: 9, Name: "ünicode", Note: &note, hidden: true},
+	}
+
+	assert.Equal(t, ""+
+		"| id  | name    | email              | tags  | Note        |\n"+
+		"| --- | ------- | ------------------ | ----- | ----------- |\n"+
+		"| 10  | gopher  | gopher@example.com | [\"a\"] |             |\n"+
+		"| 9   | ünicode |                    | null  | a \\| b<br>c |\n",
+		renderTableString(t, users))
+
+	assert.Equal(t, ""+
+		"name    | id\n"+
+		"------- | --\n"+
+		"ünicode | 9\n"+
+		"gopher  | 10\n",
+		renderTableString(t, users, WithTableFormat(TablePlain), WithTableColumns("name", "id"), WithTableSortKey("id")))
+
+	assert.Equal(t, ""+
+		"id | Note\n"+
+		"-- | -----------\n"+
+		"10 | \n"+
+		"9  | a \\| b<br>c\n",
+		renderTableString(t, users, WithTableFormat(TablePlain), WithTableColumns("id", "Note")))
+
+	_, err := New(t, WithTableColumns("missing")).renderTable(users)
+	assert.EqualError(t, err, `unknown table column "missing"`)
+
+	_, err = New(t, WithTableSortKey("missing")).renderTable(users)
+	assert.EqualError(t, err, `unknown table sort key "missing"`)
+}
+
+type tableLabel struct {
+	label string
+}
+
+func (l *tableLabel) String() string {
+	return "label " + l.label
+}
+
+func TestFormatCell(t *testing.T) {
+	var missing *tableLabel
+	assert.Equal(t, "label x", formatCell(&tableLabel{label: "x"}))
+	assert.Equal(t, "", formatCell(missing))
+	assert.Equal(t, "", formatCell(nil))
+	assert.Equal(t, "2023-06-03T01:02:03Z", formatCell(time.Date(2023, 6, 3, 1, 2, 3, 0, time.UTC)))
+	assert.Equal(t, `{"a":1}`, formatCell(map[string]int{"a": 1}))
+}
+
+func TestRenderTableMaps(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"b": 2, "a": "x"},
+		{"c": true, "a": "w"},
+	}
+
+	assert.Equal(t, ""+
+		"| a   | b   | c    |\n"+
+		"| --- | --- | ---- |\n"+
+		"| w   |     | true |\n"+
+		"| x   | 2   |      |\n",
+		renderTableString(t, rows, WithTableSortKey("a")))
+
+	_, err := New(t).renderTable(map[string]int{})
+	assert.EqualError(t, err, "table rows must be a slice or an array, got map[string]int")
+
+	_, err = New(t).renderTable([]int{1})
+	assert.EqualError(t, err, "table row 0: rows must be structs or maps, got int")
+}
+
+func TestRenderTableInterfaces(t *testing.T) {
+	rows := []interface{}{
+		tableUser{ID: 1, Name: "gopher"},
+		&tableUser{ID: 2, Name: "bitter"},
+		nil,
+	}
+
+	assert.Equal(t, ""+
+		"id | name   | email | tags | Note\n"+
+		"-- | ------ | ----- | ---- | ----\n"+
+		"1  | gopher |       | null | \n"+
+		"2  | bitter |       | null | \n"+
+		"   |        |       |      | \n",
+		renderTableString(t, rows, WithTableFormat(TablePlain)))
+
+	assert.Equal(t, ""+
+		"a | b\n"+
+		"- | -\n"+
+		"x | 2\n",
+		renderTableString(t, []interface{}{map[string]interface{}{"b": 2, "a": "x"}}, WithTableFormat(TablePlain)))
+
+	_, err := New(t).renderTable([]interface{}{tableUser{}, map[string]interface{}{}})
+	assert.EqualError(t, err, "table rows must be either structs or maps, not both")
+}
+
+func TestAssertTable(t *testing.T) {
+	dir := t.TempDir()
+	g := New(t, WithFixtureDir(dir), WithTableSortKey("id"))
+
+	savedUpdateState := *update
+	*update = true
+	g.AssertTable(t, "users", []*tableUser{{ID: 2, Name: "b"}, {ID: 1, Name: "a"}}, WithTableColumns("id", "name"))
+	*update = savedUpdateState
+
+	data, err := os.ReadFile(g.GoldenFileName(t, "users"))
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"| id  | name |\n"+
+		"| --- | ---- |\n"+
+		"| 1   | a    |\n"+
+		"| 2   | b    |\n", string(data))
+
+	g.AssertTable(t, "users", []tableUser{{ID: 1, Name: "a"}, {ID: 2, Name: "b"}}, WithTableColumns("id", "name"))
+}
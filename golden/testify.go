@@ -0,0 +1,117 @@
+package golden
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failFn reports a failed assertion, like assert.Fail and require.Fail do.
+type failFn func(t *testing.T, failureMessage string, msgAndArgs ...interface{}) bool
+
+// Assertions provides golden file assertions in the style of the testify
+// assert.Assertions. The assertions return whether they succeeded and report
+// failures with the same format and messages as the testify assertions.
+//
+//	ga := golden.NewAssert(t)
+//	ga.JSON("user", user, "user %d", id)
+type Assertions struct {
+	t    *testing.T
+	g    *Golden
+	fail failFn
+}
+
+// NewAssert creates golden assertions that mark the test as failed and let it
+// continue, like assert.New does. The options configure the underlying
+// tester, see New.
+func NewAssert(t *testing.T, options ...Option) *Assertions {
+	t.Helper()
+	return &Assertions{t: t, g: New(t, options...), fail: assertFail}
+}
+
+// NewRequire creates golden assertions that stop the test on the first
+// failure, like require.New does. The options configure the underlying tester,
+// see New.
+func NewRequire(t *testing.T, options ...Option) *Assertions {
+	t.Helper()
+	return &Assertions{t: t, g: New(t, options...), fail: requireFail}
+}
+
+// assertFail reports the failure and lets the test continue.
+func assertFail(t *testing.T, failureMessage string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	return assert.Fail(t, failureMessage, msgAndArgs...)
+}
+
+// requireFail reports the failure and stops the test.
+func requireFail(t *testing.T, failureMessage string, msgAndArgs ...interface{}) bool {
+	t.Helper()
+	require.Fail(t, failureMessage, msgAndArgs...)
+	return false
+}
+
+// With returns a copy of the assertions with the options applied on top of
+// the options of the tester.
+func (a *Assertions) With(options ...Option) *Assertions {
+	a.t.Helper()
+	c := *a
+	c.g = a.g.with(a.t, options)
+	return &c
+}
+
+// Bytes asserts that the actual data is equal to the golden file. If the
+// update flag is set, the golden file is updated first.
+func (a *Assertions) Bytes(name string, actualData []byte, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return a.result(a.g.check(a.t, name, actualData), msgAndArgs)
+}
+
+// JSON asserts that the indented json encoding of the actual data is equal to
+// the golden file.
+func (a *Assertions) JSON(name string, actualJSONData interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	js, err := json.MarshalIndent(actualJSONData, "", "  ")
+	if err != nil {
+		return a.result(err, msgAndArgs)
+	}
+
+	return a.result(a.g.withContentType("application/json").check(a.t, name, js), msgAndArgs)
+}
+
+// XML asserts that the indented xml encoding of the actual data is equal to
+// the golden file. With WithCanonicalXML, both documents are canonicalized
+// before the comparison.
+func (a *Assertions) XML(name string, actualXMLData interface{}, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	x, err := xml.MarshalIndent(actualXMLData, "", "  ")
+	if err != nil {
+		return a.result(err, msgAndArgs)
+	}
+
+	if a.g.canonicalXML {
+		return a.result(a.g.checkMarkup(a.t, name, x, xmlMarkup), msgAndArgs)
+	}
+
+	return a.result(a.g.withContentType("application/xml").check(a.t, name, x), msgAndArgs)
+}
+
+// Template asserts that the actual data is equal to the golden file after
+// executing it as a template with the data parameter, see
+// Golden.AssertWithTemplate.
+func (a *Assertions) Template(name string, data interface{}, actualData []byte, msgAndArgs ...interface{}) bool {
+	a.t.Helper()
+	return a.result(a.g.checkTemplate(a.t, name, data, actualData), msgAndArgs)
+}
+
+// result reports the error of a check.
+func (a *Assertions) result(err error, msgAndArgs []interface{}) bool {
+	a.t.Helper()
+	if err != nil {
+		return a.fail(a.t, err.Error(), msgAndArgs...)
+	}
+
+	return true
+}
@@ -0,0 +1,51 @@
+package golden
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertions(t *testing.T) {
+	dir := t.TempDir()
+	golden := NewAssert(t, WithFixtureDir(dir))
+
+	savedUpdateState := *update
+	*update = true
+	assert.True(t, golden.Bytes("bytes", []byte("abc")))
+	assert.True(t, golden.JSON("json", map[string]int{"a": 1}))
+	assert.True(t, golden.XML("xml", book{ID: "1", Title: "Go"}))
+	*update = savedUpdateState
+
+	assert.True(t, golden.Bytes("bytes", []byte("abc")))
+	assert.True(t, golden.JSON("json", map[string]int{"a": 1}))
+	assert.True(t, golden.XML("xml", book{ID: "1", Title: "Go"}))
+	assert.True(t, golden.With(WithCanonicalXML(true)).XML("xml", book{ID: "1", Title: "Go"}))
+
+	require.NoError(t, os.WriteFile(golden.g.GoldenFileName(t, "tmpl"), []byte("Hello {{ .Name }}"), 0644))
+	assert.True(t, golden.Template("tmpl", struct{ Name string }{"gopher"}, []byte("Hello gopher")))
+}
+
+func TestAssertionsFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	var messages []string
+	golden := NewAssert(t, WithFixtureDir(dir))
+	golden.fail = func(_ *testing.T, failureMessage string, msgAndArgs ...interface{}) bool {
+		messages = append(messages, failureMessage+"|"+fmt.Sprint(msgAndArgs...))
+		return false
+	}
+
+	assert.False(t, golden.Bytes("missing", []byte("abc"), "user %d", 1))
+	require.NoError(t, os.WriteFile(golden.g.GoldenFileName(t, "bytes"), []byte("abc"), 0644))
+	assert.False(t, golden.Bytes("bytes", []byte("abd")))
+	assert.False(t, golden.JSON("json", make(chan int)))
+
+	require.Len(t, messages, 3)
+	assert.Equal(t, "Golden fixture not found. Try running with -update flag.|user %d1", messages[0])
+	assert.Contains(t, messages[1], "Result did not match the golden fixture.")
+	assert.Contains(t, messages[2], "unsupported type: chan int")
+}
@@ -0,0 +1,111 @@
+package golden
+
+import (
+	"bytes"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// goVersionPattern extracts the major and minor version from runtime.Version.
+var goVersionPattern = regexp.MustCompile(`go[0-9]+\.[0-9]+`)
+
+// GoVersion returns the major and minor version of the Go release running the
+// tests, e.g. `go1.21`. It's meant to be used as fixture variant:
+//
+//	golden.New(t, golden.WithFixtureVariants(golden.GoVersion(), runtime.GOOS))
+func GoVersion() string {
+	if v := goVersionPattern.FindString(runtime.Version()); v != "" {
+		return v
+	}
+	return runtime.Version()
+}
+
+// fileNameFn returns the file name of a fixture, e.g. GoldenFileName.
+type fileNameFn func(t *testing.T, name string) string
+
+// fixtureCandidates returns the fixture file names in resolution order: the
+// most specific variant first and the generic fixture last. For the variants
+// `go1.21` and `linux` these are `name.go1.21.linux.golden`,
+// `name.linux.golden` and `name.golden`.
+func (g *Golden) fixtureCandidates(t *testing.T, name string, fileName fileNameFn) []string {
+	candidates := make([]string, 0, len(g.fixtureVariants)+1)
+	for i := range g.fixtureVariants {
+		candidates = append(candidates, fileName(t, name+"."+strings.Join(g.fixtureVariants[i:], ".")))
+	}
+	return append(candidates, fileName(t, name))
+}
+
+// resolveFixture returns the most specific fixture that exists. If there is
+// none, the generic fixture name is returned.
+func (g *Golden) resolveFixture(t *testing.T, name string, fileName fileNameFn) string {
+	candidates := g.fixtureCandidates(t, name, fileName)
+	for _, candidate := range candidates {
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// updateTarget returns the fixture variant the data must be written to. The
+// most specific variant is only written if the data differs from the less
+// specific fixture the variant would fall back to; in that case an empty name
+// is returned and a stale variant that equals its fallback is removed. If no
+// fallback exists, the most specific variant is returned if it exists and the
+// generic fixture otherwise.
+//
+// The data must already be normalized; normalize is applied to the existing
+// fallback fixture before the comparison and may be nil for binary fixtures.
+func (g *Golden) updateTarget(t *testing.T, name string, data []byte, fileName fileNameFn, normalize func([]byte) []byte) (string, error) {
+	return g.updateTargetFn(t, name, fileName, func(fallback string) (bool, error) {
+		existing, err := readFixture(fallback)
+		if err != nil {
+			return false, err
+		}
+
+		if normalize != nil {
+			existing = normalize(existing)
+		}
+
+		return bytes.Equal(existing, data), nil
+	})
+}
+
+// updateTargetFn implements updateTarget. equal reports whether the data
+// equals an existing fallback fixture and returns the error of os.Open or
+// os.ReadFile untouched, so missing fallbacks can be skipped.
+func (g *Golden) updateTargetFn(t *testing.T, name string, fileName fileNameFn, equal func(fallback string) (bool, error)) (string, error) {
+	candidates := g.fixtureCandidates(t, name, fileName)
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+
+	specific := candidates[0]
+	for _, fallback := range candidates[1:] {
+		same, err := equal(fallback)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		if !same {
+			return specific, nil
+		}
+
+		if err := os.Remove(specific); err != nil && !os.IsNotExist(err) {
+			return "", err
+		}
+		return "", nil
+	}
+
+	// a stale variant without fallback would shadow a new generic fixture
+	if _, err := os.Stat(specific); err == nil {
+		return specific, nil
+	}
+	return candidates[len(candidates)-1], nil
+}
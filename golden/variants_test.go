@@ -0,0 +1,84 @@
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoVersion(t *testing.T) {
+	assert.Regexp(t, regexp.MustCompile(`^go[0-9]+\.[0-9]+$`), GoVersion())
+}
+
+func TestFixtureCandidates(t *testing.T) {
+	g := New(t, WithFixtureVariants("go1.21", "linux"))
+	assert.Equal(t, []string{
+		filepath.Join(defaultFixtureDir, "name.go1.21.linux.golden"),
+		filepath.Join(defaultFixtureDir, "name.linux.golden"),
+		filepath.Join(defaultFixtureDir, "name.golden"),
+	}, g.fixtureCandidates(t, "name", g.GoldenFileName))
+
+	g = New(t)
+	assert.Equal(t, []string{filepath.Join(defaultFixtureDir, "name.golden")},
+		g.fixtureCandidates(t, "name", g.GoldenFileName))
+}
+
+func TestWithFixtureVariantsInvalid(t *testing.T) {
+	g := &Golden{}
+	assert.Error(t, g.WithFixtureVariants(""))
+	assert.Error(t, g.WithFixtureVariants("linux/amd64"))
+}
+
+func TestVariantResolutionAndUpdate(t *testing.T) {
+	generic := New(t)
+	variant := New(t, WithFixtureVariants("go1.21", "linux"))
+	specific := filepath.Join(defaultFixtureDir, "errors.go1.21.linux.golden")
+	osOnly := filepath.Join(defaultFixtureDir, "errors.linux.golden")
+	defer func() {
+		assert.Nil(t, os.RemoveAll(defaultFixtureDir))
+	}()
+
+	// without any fixture, the generic one is written
+	require.NoError(t, variant.Update(t, "errors", []byte("no such file")))
+	assert.FileExists(t, generic.GoldenFileName(t, "errors"))
+	assert.NoFileExists(t, specific)
+	assert.Equal(t, generic.GoldenFileName(t, "errors"), variant.resolveFixture(t, "errors", variant.GoldenFileName))
+
+	// the same output does not create a variant
+	require.NoError(t, variant.Update(t, "errors", []byte("no such file")))
+	assert.NoFileExists(t, specific)
+
+	// a different output is written to the most specific variant
+	require.NoError(t, variant.Update(t, "errors", []byte("file does not exist")))
+	assert.FileExists(t, specific)
+	assert.Equal(t, specific, variant.resolveFixture(t, "errors", variant.GoldenFileName))
+	assert.Nil(t, variant.compare(t, "errors", []byte("file does not exist")))
+	assert.Nil(t, generic.compare(t, "errors", []byte("no such file")))
+
+	// a less specific variant is used as fallback
+	require.NoError(t, os.Remove(specific))
+	require.NoError(t, os.WriteFile(osOnly, []byte("linux error"), 0600))
+	assert.Nil(t, variant.compare(t, "errors", []byte("linux error")))
+
+	// a stale variant equal to its fallback is removed
+	require.NoError(t, os.WriteFile(specific, []byte("old"), 0600))
+	require.NoError(t, variant.Update(t, "errors", []byte("linux error")))
+	assert.NoFileExists(t, specific)
+}
+
+func TestVariantUpdateWithoutFallback(t *testing.T) {
+	dir := t.TempDir()
+	variant := New(t, WithFixtureDir(dir), WithFixtureVariants("go1.21", "linux"))
+	specific := filepath.Join(dir, "errors.go1.21.linux.golden")
+
+	// a stale variant without any fallback is updated in place
+	require.NoError(t, os.WriteFile(specific, []byte("stale"), 0600))
+	require.NoError(t, variant.Update(t, "errors", []byte("fresh")))
+	assert.NoFileExists(t, filepath.Join(dir, "errors.golden"))
+	assert.Equal(t, specific, variant.resolveFixture(t, "errors", variant.GoldenFileName))
+	assert.Nil(t, variant.compare(t, "errors", []byte("fresh")))
+}